@@ -0,0 +1,517 @@
+// capture_reader.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"slices"
+	"time"
+)
+
+// DefaultCapturePorts lists the UDP ports used by NSDP (host and device ports of protocol versions 1 and 2).
+var DefaultCapturePorts = []uint16{63321, 63322, 63323, 63324}
+
+// CapturedDatagram represents a single UDP datagram extracted from a capture file.
+type CapturedDatagram struct {
+	Timestamp   time.Time    // Capture timestamp
+	Source      *net.UDPAddr // Source address
+	Destination *net.UDPAddr // Destination address
+	Data        []byte       // UDP payload
+	Message     *Message     // Decoded message (nil if decoding failed)
+	Err         error        // Decoding error (nil if decoding succeeded)
+}
+
+// CaptureReader extracts NSDP messages from pcap or pcapng capture files (as written by tcpdump or Wireshark).
+//
+// Supported link types are Ethernet (including VLAN tagged frames), raw IP, BSD loopback and Linux cooked
+// captures (v1 and v2). Packets of other link types, non-UDP packets and fragmented IP packets are skipped.
+type CaptureReader struct {
+	Ports  []uint16 // UDP ports to extract (defaults to DefaultCapturePorts; empty accepts any port)
+	Strict bool     // Rejects messages containing TLVs of unrecognized type (defaults to false)
+	source capturePacketSource
+}
+
+type capturePacketSource interface {
+	nextPacket() (*capturePacket, error)
+}
+
+type capturePacket struct {
+	linkType  uint32
+	timestamp time.Time
+	data      []byte
+}
+
+const maxCaptureBlockLength uint32 = 16 * 1024 * 1024
+
+// NewCaptureReader creates a new capture reader for the given input.
+//
+// The capture format (pcap or pcapng) is detected automatically.
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture file magic; cause: %w", err)
+	}
+	var source capturePacketSource
+	if binary.BigEndian.Uint32(magic) == pcapngBlockTypeSHB {
+		source = &pcapngReader{reader: br}
+	} else {
+		source, err = newPcapReader(br)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &CaptureReader{
+		Ports:  DefaultCapturePorts,
+		source: source,
+	}, nil
+}
+
+// Next gets the next NSDP datagram from the capture.
+//
+// If the datagram cannot be decoded into a message, the datagram is returned nevertheless with its Err field
+// set. io.EOF is returned after the last datagram.
+func (r *CaptureReader) Next() (*CapturedDatagram, error) {
+	for {
+		packet, err := r.source.nextPacket()
+		if err != nil {
+			return nil, err
+		}
+		datagram := decodeCapturePacket(packet)
+		if datagram == nil || !r.acceptsDatagram(datagram) {
+			continue
+		}
+		if r.Strict {
+			datagram.Message, datagram.Err = UnmarshalMessageStrict(datagram.Data)
+		} else {
+			datagram.Message, datagram.Err = UnmarshalMessage(datagram.Data)
+		}
+		return datagram, nil
+	}
+}
+
+// ReadAll gets all remaining NSDP datagrams from the capture.
+func (r *CaptureReader) ReadAll() ([]*CapturedDatagram, error) {
+	datagrams := make([]*CapturedDatagram, 0)
+	for {
+		datagram, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return datagrams, nil
+		}
+		if err != nil {
+			return datagrams, err
+		}
+		datagrams = append(datagrams, datagram)
+	}
+}
+
+func (r *CaptureReader) acceptsDatagram(datagram *CapturedDatagram) bool {
+	if len(r.Ports) == 0 {
+		return true
+	}
+	return slices.Contains(r.Ports, uint16(datagram.Source.Port)) || slices.Contains(r.Ports, uint16(datagram.Destination.Port))
+}
+
+const (
+	linkTypeNull      uint32 = 0
+	linkTypeEthernet  uint32 = 1
+	linkTypeRaw       uint32 = 101
+	linkTypeLinuxSLL  uint32 = 113
+	linkTypeIPv4      uint32 = 228
+	linkTypeIPv6      uint32 = 229
+	linkTypeLinuxSLL2 uint32 = 276
+)
+
+const (
+	etherTypeIPv4  uint16 = 0x0800
+	etherTypeIPv6  uint16 = 0x86dd
+	etherTypeVLAN  uint16 = 0x8100
+	etherTypeQinQ  uint16 = 0x88a8
+	ipProtocolUDP  uint8  = 17
+	udpHeaderSize  int    = 8
+	ipv4HeaderSize int    = 20
+	ipv6HeaderSize int    = 40
+)
+
+func decodeCapturePacket(packet *capturePacket) *CapturedDatagram {
+	data := packet.data
+	switch packet.linkType {
+	case linkTypeNull:
+		if len(data) < 4 {
+			return nil
+		}
+		data = data[4:]
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil
+		}
+		etherType := binary.BigEndian.Uint16(data[12:14])
+		data = data[14:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			if len(data) < 4 {
+				return nil
+			}
+			etherType = binary.BigEndian.Uint16(data[2:4])
+			data = data[4:]
+		}
+		if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+			return nil
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil
+		}
+		data = data[16:]
+	case linkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return nil
+		}
+		data = data[20:]
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+	default:
+		return nil
+	}
+	datagram := decodeIPPacket(data)
+	if datagram != nil {
+		datagram.Timestamp = packet.timestamp
+	}
+	return datagram
+}
+
+func decodeIPPacket(data []byte) *CapturedDatagram {
+	if len(data) < 1 {
+		return nil
+	}
+	var sourceIP, destinationIP net.IP
+	switch data[0] >> 4 {
+	case 4:
+		headerLength := int(data[0]&0x0f) * 4
+		if headerLength < ipv4HeaderSize || len(data) < headerLength || data[9] != ipProtocolUDP {
+			return nil
+		}
+		// Skip fragments (more fragments flag or non-zero fragment offset)
+		if binary.BigEndian.Uint16(data[6:8])&0x3fff != 0 {
+			return nil
+		}
+		totalLength := int(binary.BigEndian.Uint16(data[2:4]))
+		if headerLength <= totalLength && totalLength < len(data) {
+			data = data[:totalLength]
+		}
+		sourceIP = net.IP(slices.Clone(data[12:16]))
+		destinationIP = net.IP(slices.Clone(data[16:20]))
+		data = data[headerLength:]
+	case 6:
+		if len(data) < ipv6HeaderSize || data[6] != ipProtocolUDP {
+			return nil
+		}
+		payloadLength := int(binary.BigEndian.Uint16(data[4:6]))
+		if ipv6HeaderSize+payloadLength < len(data) {
+			data = data[:ipv6HeaderSize+payloadLength]
+		}
+		sourceIP = net.IP(slices.Clone(data[8:24]))
+		destinationIP = net.IP(slices.Clone(data[24:40]))
+		data = data[ipv6HeaderSize:]
+	default:
+		return nil
+	}
+	if len(data) < udpHeaderSize {
+		return nil
+	}
+	udpLength := int(binary.BigEndian.Uint16(data[4:6]))
+	if udpHeaderSize <= udpLength && udpLength < len(data) {
+		data = data[:udpLength]
+	}
+	return &CapturedDatagram{
+		Source:      &net.UDPAddr{IP: sourceIP, Port: int(binary.BigEndian.Uint16(data[0:2]))},
+		Destination: &net.UDPAddr{IP: destinationIP, Port: int(binary.BigEndian.Uint16(data[2:4]))},
+		Data:        slices.Clone(data[udpHeaderSize:]),
+	}
+}
+
+const (
+	pcapMagicMicros        uint32 = 0xa1b2c3d4
+	pcapMagicNanos         uint32 = 0xa1b23c4d
+	pcapFileHeaderSize     int    = 24
+	pcapRecordHeaderSize   int    = 16
+	pcapngBlockTypeSHB     uint32 = 0x0a0d0d0a
+	pcapngBlockTypeIDB     uint32 = 0x00000001
+	pcapngBlockTypeSPB     uint32 = 0x00000003
+	pcapngBlockTypeEPB     uint32 = 0x00000006
+	pcapngByteOrderMagic   uint32 = 0x1a2b3c4d
+	pcapngOptionEnd        uint16 = 0
+	pcapngOptionTSResol    uint16 = 9
+	pcapngOptionTSOffset   uint16 = 14
+	pcapngDefaultTSResol   uint8  = 6
+	pcapngBlockHeaderSize  uint32 = 8
+	pcapngBlockTrailerSize uint32 = 4
+)
+
+type pcapReader struct {
+	reader    io.Reader
+	byteOrder binary.ByteOrder
+	nanos     bool
+	linkType  uint32
+}
+
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	header := make([]byte, pcapFileHeaderSize)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pcap file header; cause: %w", err)
+	}
+	reader := &pcapReader{reader: r}
+	switch {
+	case binary.LittleEndian.Uint32(header) == pcapMagicMicros:
+		reader.byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == pcapMagicMicros:
+		reader.byteOrder = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == pcapMagicNanos:
+		reader.byteOrder = binary.LittleEndian
+		reader.nanos = true
+	case binary.BigEndian.Uint32(header) == pcapMagicNanos:
+		reader.byteOrder = binary.BigEndian
+		reader.nanos = true
+	default:
+		return nil, fmt.Errorf("unrecognized capture file magic: %08xh", binary.BigEndian.Uint32(header))
+	}
+	// The upper bits of the link type field may carry FCS information
+	reader.linkType = reader.byteOrder.Uint32(header[20:24]) & 0x0000ffff
+	return reader, nil
+}
+
+func (r *pcapReader) nextPacket() (*capturePacket, error) {
+	header := make([]byte, pcapRecordHeaderSize)
+	_, err := io.ReadFull(r.reader, header)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pcap record header; cause: %w", err)
+	}
+	seconds := r.byteOrder.Uint32(header[0:4])
+	fraction := r.byteOrder.Uint32(header[4:8])
+	capturedLength := r.byteOrder.Uint32(header[8:12])
+	if capturedLength > maxCaptureBlockLength {
+		return nil, fmt.Errorf("invalid pcap record length: %d", capturedLength)
+	}
+	data := make([]byte, capturedLength)
+	_, err = io.ReadFull(r.reader, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pcap record; cause: %w", err)
+	}
+	nanos := int64(fraction)
+	if !r.nanos {
+		nanos *= 1000
+	}
+	return &capturePacket{
+		linkType:  r.linkType,
+		timestamp: time.Unix(int64(seconds), nanos),
+		data:      data,
+	}, nil
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	snapLen  uint32
+	tsResol  uint8
+	tsOffset int64
+}
+
+type pcapngReader struct {
+	reader     io.Reader
+	byteOrder  binary.ByteOrder
+	interfaces []*pcapngInterface
+}
+
+func (r *pcapngReader) nextPacket() (*capturePacket, error) {
+	for {
+		blockType, body, err := r.readBlock()
+		if err != nil {
+			return nil, err
+		}
+		switch blockType {
+		case pcapngBlockTypeIDB:
+			err = r.addInterface(body)
+			if err != nil {
+				return nil, err
+			}
+		case pcapngBlockTypeEPB:
+			return r.decodeEnhancedPacket(body)
+		case pcapngBlockTypeSPB:
+			return r.decodeSimplePacket(body)
+		}
+	}
+}
+
+func (r *pcapngReader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, pcapngBlockHeaderSize)
+	_, err := io.ReadFull(r.reader, header)
+	if errors.Is(err, io.EOF) {
+		return 0, nil, io.EOF
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read pcapng block header; cause: %w", err)
+	}
+	if binary.BigEndian.Uint32(header[0:4]) == pcapngBlockTypeSHB {
+		// A new section starts; the byte order is defined by the section header
+		magic := make([]byte, 4)
+		_, err = io.ReadFull(r.reader, magic)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read pcapng section header; cause: %w", err)
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == pcapngByteOrderMagic:
+			r.byteOrder = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == pcapngByteOrderMagic:
+			r.byteOrder = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("unrecognized pcapng byte order magic: %08xh", binary.BigEndian.Uint32(magic))
+		}
+		r.interfaces = r.interfaces[:0]
+		_, err = r.readBlockBody(header, 4)
+		if err != nil {
+			return 0, nil, err
+		}
+		return pcapngBlockTypeSHB, nil, nil
+	}
+	if r.byteOrder == nil {
+		return 0, nil, errors.New("missing pcapng section header")
+	}
+	body, err := r.readBlockBody(header, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+	return r.byteOrder.Uint32(header[0:4]), body, nil
+}
+
+func (r *pcapngReader) readBlockBody(header []byte, consumed uint32) ([]byte, error) {
+	blockLength := r.byteOrder.Uint32(header[4:8])
+	if blockLength < pcapngBlockHeaderSize+consumed+pcapngBlockTrailerSize || blockLength%4 != 0 || blockLength > maxCaptureBlockLength {
+		return nil, fmt.Errorf("invalid pcapng block length: %d", blockLength)
+	}
+	body := make([]byte, blockLength-pcapngBlockHeaderSize-consumed)
+	_, err := io.ReadFull(r.reader, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pcapng block; cause: %w", err)
+	}
+	return body[:len(body)-int(pcapngBlockTrailerSize)], nil
+}
+
+func (r *pcapngReader) addInterface(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("invalid pcapng interface description block length: %d", len(body))
+	}
+	iface := &pcapngInterface{
+		linkType: uint32(r.byteOrder.Uint16(body[0:2])),
+		snapLen:  r.byteOrder.Uint32(body[4:8]),
+		tsResol:  pcapngDefaultTSResol,
+	}
+	options := body[8:]
+	for len(options) >= 4 {
+		code := r.byteOrder.Uint16(options[0:2])
+		length := int(r.byteOrder.Uint16(options[2:4]))
+		if code == pcapngOptionEnd || len(options) < 4+length {
+			break
+		}
+		value := options[4 : 4+length]
+		switch {
+		case code == pcapngOptionTSResol && length == 1:
+			iface.tsResol = value[0]
+		case code == pcapngOptionTSOffset && length == 8:
+			iface.tsOffset = int64(r.byteOrder.Uint64(value))
+		}
+		options = options[min(len(options), 4+(length+3)&^3):]
+	}
+	r.interfaces = append(r.interfaces, iface)
+	return nil
+}
+
+func (r *pcapngReader) decodeEnhancedPacket(body []byte) (*capturePacket, error) {
+	if len(body) < 20 {
+		return nil, fmt.Errorf("invalid pcapng enhanced packet block length: %d", len(body))
+	}
+	interfaceID := r.byteOrder.Uint32(body[0:4])
+	if uint32(len(r.interfaces)) <= interfaceID {
+		return nil, fmt.Errorf("unknown pcapng interface: %d", interfaceID)
+	}
+	iface := r.interfaces[interfaceID]
+	timestamp := uint64(r.byteOrder.Uint32(body[4:8]))<<32 | uint64(r.byteOrder.Uint32(body[8:12]))
+	capturedLength := r.byteOrder.Uint32(body[12:16])
+	if uint32(len(body)-20) < capturedLength {
+		return nil, fmt.Errorf("invalid pcapng enhanced packet length: %d", capturedLength)
+	}
+	return &capturePacket{
+		linkType:  iface.linkType,
+		timestamp: iface.timestamp(timestamp),
+		data:      body[20 : 20+capturedLength],
+	}, nil
+}
+
+func (r *pcapngReader) decodeSimplePacket(body []byte) (*capturePacket, error) {
+	if len(body) < 4 {
+		return nil, fmt.Errorf("invalid pcapng simple packet block length: %d", len(body))
+	}
+	if len(r.interfaces) == 0 {
+		return nil, errors.New("unknown pcapng interface: 0")
+	}
+	iface := r.interfaces[0]
+	capturedLength := min(uint32(len(body)-4), r.byteOrder.Uint32(body[0:4]))
+	if iface.snapLen != 0 {
+		capturedLength = min(capturedLength, iface.snapLen)
+	}
+	return &capturePacket{
+		linkType: iface.linkType,
+		data:     body[4 : 4+capturedLength],
+	}, nil
+}
+
+func (iface *pcapngInterface) timestamp(units uint64) time.Time {
+	var seconds, nanos uint64
+	if iface.tsResol&0x80 == 0 {
+		exponent := iface.tsResol
+		if exponent > 19 {
+			exponent = 19
+		}
+		scale := uint64(1)
+		for range exponent {
+			scale *= 10
+		}
+		seconds = units / scale
+		fraction := units % scale
+		if exponent <= 9 {
+			for range 9 - exponent {
+				fraction *= 10
+			}
+		} else {
+			for range exponent - 9 {
+				fraction /= 10
+			}
+		}
+		nanos = fraction
+	} else {
+		exponent := uint(iface.tsResol & 0x7f)
+		if exponent > 63 {
+			exponent = 63
+		}
+		seconds = units >> exponent
+		fraction := units & (1<<exponent - 1)
+		hi, lo := bits.Mul64(fraction, uint64(time.Second))
+		if exponent == 0 {
+			nanos = 0
+		} else {
+			nanos = hi<<(64-exponent) | lo>>exponent
+		}
+	}
+	return time.Unix(int64(seconds)+iface.tsOffset, int64(nanos))
+}
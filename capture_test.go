@@ -0,0 +1,138 @@
+// capture_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestCaptureReaderPcap(t *testing.T) {
+	runCaptureReaderTest(t, "testdata/discovery.pcap")
+}
+
+func TestCaptureReaderPcapng(t *testing.T) {
+	runCaptureReaderTest(t, "testdata/discovery.pcapng")
+}
+
+func TestCaptureReaderAllPorts(t *testing.T) {
+	file, err := os.Open("testdata/discovery.pcapng")
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := nsdp.NewCaptureReader(file)
+	require.NoError(t, err)
+	reader.Ports = nil
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 4, len(datagrams))
+	require.Equal(t, 53, datagrams[2].Source.Port)
+	require.Nil(t, datagrams[2].Message)
+	require.Error(t, datagrams[2].Err)
+}
+
+func TestCaptureReaderInvalid(t *testing.T) {
+	_, err := nsdp.NewCaptureReader(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04}))
+	require.Error(t, err)
+	_, err = nsdp.NewCaptureReader(bytes.NewReader(nil))
+	require.Error(t, err)
+}
+
+func TestCaptureWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	timestamp := time.Unix(1700000000, 123456000)
+	host4 := &net.UDPAddr{IP: net.IP{10, 1, 0, 2}, Port: 63321}
+	device4 := &net.UDPAddr{IP: net.IP{10, 1, 0, 3}, Port: 63322}
+	host6 := &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 63323}
+	device6 := &net.UDPAddr{IP: net.ParseIP("fe80::2"), Port: 63324}
+	response, err := hex.DecodeString(connTestResponseSwitch1)
+	require.NoError(t, err)
+	err = writer.WriteDatagram(timestamp, device4, host4, response)
+	require.NoError(t, err)
+	err = writer.WriteDatagram(timestamp, host6, device6, []byte{0x01})
+	require.NoError(t, err)
+	reader, err := nsdp.NewCaptureReader(buffer)
+	require.NoError(t, err)
+	datagram, err := reader.Next()
+	require.NoError(t, err)
+	require.True(t, timestamp.Equal(datagram.Timestamp))
+	require.Equal(t, device4.String(), datagram.Source.String())
+	require.Equal(t, host4.String(), datagram.Destination.String())
+	require.Equal(t, response, datagram.Data)
+	require.NoError(t, datagram.Err)
+	require.Equal(t, response, datagram.Message.Marshal())
+	datagram, err = reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, host6.String(), datagram.Source.String())
+	require.Equal(t, device6.String(), datagram.Destination.String())
+	require.Equal(t, []byte{0x01}, datagram.Data)
+	require.Error(t, datagram.Err)
+	_, err = reader.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestConnCapture(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	buffer := &bytes.Buffer{}
+	conn.Capture, err = nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	reader, err := nsdp.NewCaptureReader(buffer)
+	require.NoError(t, err)
+	reader.Ports = nil
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(datagrams))
+	require.Equal(t, nsdp.ReadRequest, datagrams[0].Message.Header.Operation)
+	require.Equal(t, nsdp.ReadResponse, datagrams[1].Message.Header.Operation)
+	for _, response := range responses {
+		require.Equal(t, response.Marshal(), datagrams[1].Data)
+	}
+}
+
+func runCaptureReaderTest(t *testing.T, name string) {
+	file, err := os.Open(name)
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := nsdp.NewCaptureReader(file)
+	require.NoError(t, err)
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(datagrams))
+	for _, datagram := range datagrams {
+		require.NoError(t, datagram.Err)
+	}
+	require.Equal(t, nsdp.ReadRequest, datagrams[0].Message.Header.Operation)
+	require.Equal(t, "10.1.0.2:63321", datagrams[0].Source.String())
+	require.Equal(t, "255.255.255.255:63322", datagrams[0].Destination.String())
+	require.True(t, time.Unix(1700000000, 123456000).Equal(datagrams[0].Timestamp))
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch1).Marshal(), datagrams[1].Message.Marshal())
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch2).Marshal(), datagrams[2].Message.Marshal())
+	require.True(t, time.Unix(1700000000, 456789000).Equal(datagrams[2].Timestamp))
+}
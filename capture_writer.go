@@ -0,0 +1,143 @@
+// capture_writer.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// CaptureWriter records UDP datagrams to a pcap capture file.
+//
+// As the link layer information is not available to a UDP socket, the datagrams are recorded as raw IP packets
+// with synthesized IPv4 (or IPv6) and UDP headers. The resulting file can be read by CaptureReader as well as by
+// common tools like tcpdump or Wireshark.
+//
+// A CaptureWriter is safe for concurrent use and may be shared between multiple connections (see Conn.Capture).
+type CaptureWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+const pcapVersionMajor uint16 = 2
+const pcapVersionMinor uint16 = 4
+const pcapSnapLen uint32 = 65535
+
+// NewCaptureWriter creates a new capture writer for the given output and writes the capture file header.
+func NewCaptureWriter(w io.Writer) (*CaptureWriter, error) {
+	header := make([]byte, 0, pcapFileHeaderSize)
+	header = binary.LittleEndian.AppendUint32(header, pcapMagicMicros)
+	header = binary.LittleEndian.AppendUint16(header, pcapVersionMajor)
+	header = binary.LittleEndian.AppendUint16(header, pcapVersionMinor)
+	header = binary.LittleEndian.AppendUint32(header, 0)
+	header = binary.LittleEndian.AppendUint32(header, 0)
+	header = binary.LittleEndian.AppendUint32(header, pcapSnapLen)
+	header = binary.LittleEndian.AppendUint32(header, linkTypeRaw)
+	_, err := w.Write(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write pcap file header; cause: %w", err)
+	}
+	return &CaptureWriter{writer: w}, nil
+}
+
+// WriteDatagram records a single UDP datagram sent from the given source to the given destination address.
+func (w *CaptureWriter) WriteDatagram(timestamp time.Time, source *net.UDPAddr, destination *net.UDPAddr, data []byte) error {
+	packet, err := appendIPPacket(make([]byte, pcapRecordHeaderSize, pcapRecordHeaderSize+ipv6HeaderSize+udpHeaderSize+len(data)), source, destination, data)
+	if err != nil {
+		return err
+	}
+	packetLength := uint32(len(packet) - pcapRecordHeaderSize)
+	binary.LittleEndian.PutUint32(packet[0:4], uint32(timestamp.Unix()))
+	binary.LittleEndian.PutUint32(packet[4:8], uint32(timestamp.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(packet[8:12], min(packetLength, pcapSnapLen))
+	binary.LittleEndian.PutUint32(packet[12:16], packetLength)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err = w.writer.Write(packet[:pcapRecordHeaderSize+int(min(packetLength, pcapSnapLen))])
+	if err != nil {
+		return fmt.Errorf("failed to write pcap record; cause: %w", err)
+	}
+	return nil
+}
+
+func appendIPPacket(packet []byte, source *net.UDPAddr, destination *net.UDPAddr, data []byte) ([]byte, error) {
+	udpLength := udpHeaderSize + len(data)
+	if udpLength > 0xffff {
+		return nil, fmt.Errorf("datagram too large: %d", len(data))
+	}
+	var pseudoHeader []byte
+	sourceIP4 := source.IP.To4()
+	destinationIP4 := destination.IP.To4()
+	if sourceIP4 != nil && destinationIP4 != nil {
+		ipStart := len(packet)
+		packet = append(packet, 0x45, 0x00)
+		packet = binary.BigEndian.AppendUint16(packet, uint16(ipv4HeaderSize+udpLength))
+		packet = append(packet, 0x00, 0x00, 0x40, 0x00, 64, ipProtocolUDP, 0x00, 0x00)
+		packet = append(packet, sourceIP4...)
+		packet = append(packet, destinationIP4...)
+		binary.BigEndian.PutUint16(packet[ipStart+10:ipStart+12], internetChecksum(0, packet[ipStart:]))
+		pseudoHeader = append(pseudoHeader, sourceIP4...)
+		pseudoHeader = append(pseudoHeader, destinationIP4...)
+		pseudoHeader = append(pseudoHeader, 0x00, ipProtocolUDP)
+		pseudoHeader = binary.BigEndian.AppendUint16(pseudoHeader, uint16(udpLength))
+	} else {
+		sourceIP16 := source.IP.To16()
+		destinationIP16 := destination.IP.To16()
+		if sourceIP16 == nil || destinationIP16 == nil {
+			return nil, fmt.Errorf("invalid datagram addresses: %s > %s", source, destination)
+		}
+		packet = append(packet, 0x60, 0x00, 0x00, 0x00)
+		packet = binary.BigEndian.AppendUint16(packet, uint16(udpLength))
+		packet = append(packet, ipProtocolUDP, 64)
+		packet = append(packet, sourceIP16...)
+		packet = append(packet, destinationIP16...)
+		pseudoHeader = append(pseudoHeader, sourceIP16...)
+		pseudoHeader = append(pseudoHeader, destinationIP16...)
+		pseudoHeader = binary.BigEndian.AppendUint32(pseudoHeader, uint32(udpLength))
+		pseudoHeader = append(pseudoHeader, 0x00, 0x00, 0x00, ipProtocolUDP)
+	}
+	udpStart := len(packet)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(source.Port))
+	packet = binary.BigEndian.AppendUint16(packet, uint16(destination.Port))
+	packet = binary.BigEndian.AppendUint16(packet, uint16(udpLength))
+	packet = append(packet, 0x00, 0x00)
+	packet = append(packet, data...)
+	checksum := internetChecksum(internetChecksumSum(0, pseudoHeader), packet[udpStart:])
+	if checksum == 0 {
+		// A computed UDP checksum of zero is transmitted as all ones
+		checksum = 0xffff
+	}
+	binary.BigEndian.PutUint16(packet[udpStart+6:udpStart+8], checksum)
+	return packet, nil
+}
+
+func internetChecksum(sum uint32, data []byte) uint16 {
+	sum = internetChecksumSum(sum, data)
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+func internetChecksumSum(sum uint32, data []byte) uint32 {
+	for len(data) >= 2 {
+		sum += uint32(binary.BigEndian.Uint16(data))
+		data = data[2:]
+	}
+	if len(data) == 1 {
+		sum += uint32(data[0]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return sum
+}
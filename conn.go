@@ -9,12 +9,17 @@ package nsdp
 
 import (
 	"bytes"
-	"encoding/hex"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"iter"
 	"log"
 	"net"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,19 +27,44 @@ const defaultReceiveBufferSize uint = 8192
 const defaultReceiveQueueLength uint = 16
 const defaultReceiveDeviceLimit uint = 0
 const defaultReceiveTimeout time.Duration = 2000 * time.Millisecond
+const defaultDHCPRenewTimeout time.Duration = 10000 * time.Millisecond
 
 // Conn represents a network connection used for sending and receiving NSDP messages.
+//
+// A Conn is safe for concurrent use. Each request is sent with its own sequence number and is bounded by its own
+// receive timeout (and context), hence multiple requests may be in flight over the same connection at the same time.
+// The configuration fields must not be modified while requests are in flight. The settings used by the connection's
+// receive goroutine (ReceiveBufferSize, StrictDecoding, Capture and Debug) are taken over whenever a request is sent;
+// a modified ReceiveBufferSize takes effect with the next received datagram.
+//
+// If receiving fails repeatedly (for reasons other than closing the connection), the pending requests are finished
+// with the receive error and any further request fails with this error.
 type Conn struct {
 	laddr              *net.UDPAddr
 	taddr              *net.UDPAddr
 	host               net.HardwareAddr
 	conn               *net.UDPConn
+	read               datagramReadFunc
+	mutex              sync.Mutex
 	seq                Sequence
-	ReceiveBufferSize  uint          // Receive buffer size (defaults to 8192)
-	ReceiveQueueLength uint          // Receive queue length (defaults to 16)
-	ReceiveDeviceLimit uint          // Receive device limit (defaults to 0; no limit)
-	ReceiveTimeout     time.Duration // Receive timeout (defaults to 2s)
-	Debug              bool          // Enables debug output via log.Printf
+	pending            map[Sequence]*pendingRequest
+	closed             bool
+	receiveErr         error
+	receiveSettings    receiveSettings
+	receiverFinished   chan struct{}
+	ReceiveBufferSize  uint             // Receive buffer size (defaults to 8192)
+	ReceiveQueueLength uint             // Initial receive queue capacity per request (defaults to 16; the queue grows as needed)
+	ReceiveDeviceLimit uint             // Receive device limit (defaults to 0; no limit)
+	ReceiveTimeout     time.Duration    // Receive timeout (defaults to 2s)
+	DHCPRenewTimeout   time.Duration    // DHCP renew timeout (defaults to 10s)
+	Capabilities       *CapabilityCache // Capabilities consulted by high-level helpers (defaults to an empty cache; nil disables capability checks)
+	StrictDecoding     bool             // Rejects received messages containing TLVs of unrecognized type (defaults to false)
+	CheckCompleteness  bool             // Attaches a completeness report to each response (defaults to false; see CheckCompleteness)
+	Capture            *CaptureWriter   // Records all sent and received datagrams (defaults to nil; no recording)
+	DatagramBudget     uint             // Response size budget for splitting read requests (defaults to 1472; 0 disables splitting)
+	DatagramBudgets    map[string]uint  // Response size budgets by device model prefix overriding DatagramBudget (defaults to nil; no model specific budgets)
+	Retry              *RetryPolicy     // Retransmission policy for unicast requests (defaults to nil; no retransmission)
+	Debug              bool             // Enables debug output via log.Printf (including the passwords of write requests)
 }
 
 // NewConn establishes a new connection to the given remote target.
@@ -76,23 +106,68 @@ func NewConn(target string, debug bool) (*Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Conn{
+	c := &Conn{
 		laddr:              laddr,
 		taddr:              taddr,
 		host:               host,
 		conn:               conn,
 		seq:                Sequence(time.Now().UnixNano()),
+		pending:            make(map[Sequence]*pendingRequest),
+		receiverFinished:   make(chan struct{}),
 		ReceiveBufferSize:  defaultReceiveBufferSize,
 		ReceiveQueueLength: defaultReceiveQueueLength,
 		ReceiveDeviceLimit: defaultReceiveDeviceLimit,
 		ReceiveTimeout:     defaultReceiveTimeout,
+		DHCPRenewTimeout:   defaultDHCPRenewTimeout,
+		Capabilities:       NewCapabilityCache(),
+		DatagramBudget:     defaultDatagramBudget,
 		Debug:              debug,
-	}, nil
+	}
+	c.read = conn.ReadFromUDP
+	if readHook != nil {
+		c.read = readHook(c.read)
+	}
+	c.receiveSettings = c.currentReceiveSettings()
+	go c.receiveLoop()
+	return c, nil
+}
+
+// datagramReadFunc reads a single datagram (see net.UDPConn.ReadFromUDP).
+type datagramReadFunc func(b []byte) (int, *net.UDPAddr, error)
+
+// readHook wraps the datagram read function of newly created connections (used to inject read errors in tests).
+var readHook func(read datagramReadFunc) datagramReadFunc
+
+// receiveSettings holds the connection settings used by the connection's receive goroutine. The settings are taken
+// over from the connection's configuration fields whenever a request is registered (see registerRequest), hence the
+// configuration fields are never accessed by the receive goroutine.
+type receiveSettings struct {
+	bufferSize     uint
+	strictDecoding bool
+	capture        *CaptureWriter
+	debug          bool
+}
+
+func (c *Conn) currentReceiveSettings() receiveSettings {
+	return receiveSettings{
+		bufferSize:     c.ReceiveBufferSize,
+		strictDecoding: c.StrictDecoding,
+		capture:        c.Capture,
+		debug:          c.Debug,
+	}
 }
 
 // Close closes the connection.
+//
+// Pending requests are finished with net.ErrClosed. When Close returns, the connection's receive goroutine
+// has finished.
 func (c *Conn) Close() error {
-	return c.conn.Close()
+	c.mutex.Lock()
+	c.closed = true
+	c.mutex.Unlock()
+	err := c.conn.Close()
+	<-c.receiverFinished
+	return err
 }
 
 // SendReceiveMessage sends the given NSDP message and waits for responses.
@@ -100,125 +175,464 @@ func (c *Conn) Close() error {
 // The submitted message's host address and sequence number are ignored. Instead the connection state
 // is used to populate this info.
 //
-// If the message's device address is empty (00:00:00:00:00:00), an arbitrary number of response messages is returned.
+// If the message's device address is empty (00:00:00:00:00:00), an arbitrary number of response messages is returned
+// (see SendReceiveMessageStream for receiving the responses as soon as they arrive).
 // Furthermore the call will only finish after the receive timeout or the receive device limit is reached. Receiving no
 // response is not considered an error.
 //
 // If the message's device address has been set, exactly one response message is returned. Furthermore the call will
-// return as soon as a response is received. Receiving no response is considered an error.
+// return as soon as a response is received. Receiving no response is considered an error. Unanswered requests are
+// retransmitted according to the connection's retry policy (see Retry). The number of transmissions needed is reported
+// in the response's Attempts field.
+//
+// The message is validated (see Message.Validate) before it is sent.
+//
+// Read requests, whose responses are estimated to exceed the datagram budget applicable for the addressed device
+// (see DatagramBudget and DatagramBudgets), are split into several requests, each sent with its own sequence number.
+// The responses to these requests are merged per device, hence a single response message is returned per device
+// in any case. The device model is known to the connection, if the device has been probed (see ProbeCapabilities).
+// The parts of a split broadcast request are sent back to back and their responses are collected within a single
+// receive timeout. The parts of a split unicast request are sent one after another.
 //
 // The returned map is build up using the responding device's hardware address string as the key and the corresponding
-// response message as the value.
+// response message as the value. If a device answers a broadcast request with more than one datagram, the datagrams
+// are merged into a single response message. Exact duplicates of an already received datagram are not merged, but
+// counted in the response's Duplicates field.
+//
+// If any of the responding devices reports a non-zero result (see Message.Err), the received responses are returned
+// together with a DeviceErrors error containing the individual ResultError instances.
 func (c *Conn) SendReceiveMessage(msg *Message) (map[string]*Message, error) {
-	c.seq += 1
-	c.conn.SetReadDeadline(time.Now().Add(c.ReceiveTimeout))
+	return c.SendReceiveMessageContext(context.Background(), msg)
+}
+
+// SendReceiveMessageContext sends the given NSDP message and waits for responses (see SendReceiveMessage).
+//
+// In addition to the receive timeout, the call is bounded by the given context's deadline. If the context is
+// cancelled or its deadline is exceeded before the call finishes, the context's error is returned. In any case
+// the request is withdrawn when the call returns, and responses arriving afterwards are discarded by the
+// connection's receive goroutine.
+func (c *Conn) SendReceiveMessageContext(ctx context.Context, msg *Message) (map[string]*Message, error) {
+	responses := make(map[string]*Message, 0)
+	err := c.receiveResponses(ctx, msg, func(response *Message, duplicate bool) bool {
+		device := response.Header.DeviceAddress.String()
+		merged, found := responses[device]
+		if duplicate {
+			if found {
+				merged.Duplicates++
+			}
+		} else if found {
+			mergeResponse(merged, response)
+		} else {
+			responses[device] = response
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.CheckCompleteness {
+		for _, response := range responses {
+			response.Completeness = CheckCompleteness(msg, response)
+		}
+	}
+	return responses, checkMessageResults(responses)
+}
+
+// SendReceiveMessageStream sends the given NSDP message and yields the responses as soon as they are received.
+//
+// The request is processed like in SendReceiveMessageContext. However, in contrast to SendReceiveMessageContext, each
+// received datagram is yielded on its own. Hence a device answering with more than one datagram (or a request being
+// split into several requests) results in more than one yielded message for this device. Exact duplicates of an
+// already yielded datagram are skipped. Device-reported failures are not yielded as an error (see Message.Err), and
+// no completeness report is attached.
+//
+// If the request fails (including the cancellation of the given context), the error is yielded together with a nil
+// message and the iteration ends. The caller may stop the iteration at any time; the request is withdrawn in this
+// case and responses arriving afterwards are discarded.
+func (c *Conn) SendReceiveMessageStream(ctx context.Context, msg *Message) iter.Seq2[*Message, error] {
+	return func(yield func(*Message, error) bool) {
+		stopped := false
+		err := c.receiveResponses(ctx, msg, func(response *Message, duplicate bool) bool {
+			if duplicate {
+				return true
+			}
+			stopped = !yield(response, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// responseHandler is invoked for each response received by receiveResponses. Returning false stops the receiving.
+type responseHandler func(response *Message, duplicate bool) bool
+
+// receiveResponses sends the given message (split if necessary, see splitRequest) and passes each received response
+// to the given handler. Exact duplicates of an already received datagram are flagged accordingly.
+func (c *Conn) receiveResponses(ctx context.Context, msg *Message, handler responseHandler) error {
+	err := msg.Validate()
+	if err != nil {
+		return err
+	}
+	receivedDigests := make(map[[sha256.Size]byte]bool, 0)
+	checkedHandler := func(received *receiveQueueEntry) bool {
+		duplicate := receivedDigests[received.digest]
+		if duplicate && c.Debug {
+			log.Printf("NSDP %s discarding duplicate response from device %s", c.laddr, received.msg.Header.DeviceAddress)
+		}
+		receivedDigests[received.digest] = true
+		return handler(received.msg, duplicate)
+	}
+	parts := c.splitRequest(msg)
 	if bytes.Equal(msg.Header.DeviceAddress, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00}) {
-		return c.sendReceiveBroadcastMessage(msg)
+		return c.sendReceiveBroadcastParts(ctx, parts, checkedHandler)
+	}
+	for _, part := range parts {
+		continued, err := c.sendReceiveUnicastPart(ctx, part, checkedHandler)
+		if err != nil {
+			return err
+		}
+		if !continued {
+			break
+		}
 	}
-	return c.sendReceiveUnicastMessage(msg)
+	return nil
+}
+
+// sendReceiveUnicastPart sends a single unicast request and passes the received response to the given handler. The
+// returned flag indicates whether the handler requested to stop.
+func (c *Conn) sendReceiveUnicastPart(ctx context.Context, msg *Message, handler func(*receiveQueueEntry) bool) (bool, error) {
+	err := ctx.Err()
+	if err != nil {
+		return false, err
+	}
+	request, err := c.registerRequest(1)
+	if err != nil {
+		return false, err
+	}
+	defer c.unregisterRequest(request)
+	return c.sendReceiveUnicastMessage(ctx, msg, request, handler)
+}
+
+// sendReceiveBroadcastParts sends the given broadcast request parts back to back (each with its own sequence number)
+// and passes the received responses to the given handler until the receive timeout is reached.
+func (c *Conn) sendReceiveBroadcastParts(ctx context.Context, parts []*Message, handler func(*receiveQueueEntry) bool) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	request, err := c.registerRequest(len(parts))
+	if err != nil {
+		return err
+	}
+	defer c.unregisterRequest(request)
+	timeout := time.NewTimer(c.ReceiveTimeout)
+	defer timeout.Stop()
+	for i, part := range parts {
+		err = c.sendMessage(part, request.sequences[i])
+		if err != nil {
+			return err
+		}
+	}
+	return c.receiveBroadcastResponses(ctx, request, timeout, handler)
 }
 
 type receiveQueueEntry struct {
-	msg *Message
-	err error
+	msg    *Message
+	digest [sha256.Size]byte // Digest of the received datagram (used to detect exact duplicates)
+	err    error
 }
 
-func (c *Conn) sendReceiveBroadcastMessage(msg *Message) (map[string]*Message, error) {
-	receiveQueue := make(chan *receiveQueueEntry, c.ReceiveQueueLength)
-	go func() {
-		for {
-			msg, err := c.receiveMessage()
-			receiveQueue <- &receiveQueueEntry{
-				msg: msg,
-				err: err,
-			}
-			if err != nil {
-				break
-			}
+// pendingRequest represents a request waiting for its responses. The responses are dispatched to the request by
+// the connection's receive goroutine (see receiveLoop) using the request's sequence numbers (one for each part of
+// a split request). The receive queue grows as needed, hence queueing never blocks the receive goroutine and no
+// response is dropped.
+type pendingRequest struct {
+	sequences    []Sequence
+	mutex        sync.Mutex
+	receiveQueue []*receiveQueueEntry
+	notify       chan struct{}
+}
+
+func (request *pendingRequest) queue(entry *receiveQueueEntry) {
+	request.mutex.Lock()
+	request.receiveQueue = append(request.receiveQueue, entry)
+	request.mutex.Unlock()
+	select {
+	case request.notify <- struct{}{}:
+	default:
+		// Already notified
+	}
+}
+
+func (request *pendingRequest) dequeue() *receiveQueueEntry {
+	request.mutex.Lock()
+	defer request.mutex.Unlock()
+	if len(request.receiveQueue) == 0 {
+		return nil
+	}
+	entry := request.receiveQueue[0]
+	request.receiveQueue[0] = nil
+	request.receiveQueue = request.receiveQueue[1:]
+	return entry
+}
+
+// next waits for the next queued entry. If the given timeout fires first, nil is returned.
+func (request *pendingRequest) next(ctx context.Context, timeout <-chan time.Time) (*receiveQueueEntry, error) {
+	for {
+		entry := request.dequeue()
+		if entry != nil {
+			return entry, nil
 		}
-	}()
-	err := c.sendMessage(msg)
-	if err != nil {
-		return nil, err
+		select {
+		case <-request.notify:
+		case <-timeout:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Conn) registerRequest(parts int) (*pendingRequest, error) {
+	settings := c.currentReceiveSettings()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return nil, net.ErrClosed
+	}
+	if c.receiveErr != nil {
+		return nil, c.receiveErr
+	}
+	c.receiveSettings = settings
+	request := &pendingRequest{
+		sequences:    make([]Sequence, 0, parts),
+		receiveQueue: make([]*receiveQueueEntry, 0, c.ReceiveQueueLength),
+		notify:       make(chan struct{}, 1),
 	}
-	receivedMsgs := make(map[string]*Message, 0)
+	for range parts {
+		c.seq += 1
+		for c.pending[c.seq] != nil {
+			c.seq += 1
+		}
+		request.sequences = append(request.sequences, c.seq)
+		c.pending[c.seq] = request
+	}
+	return request, nil
+}
+
+func (c *Conn) unregisterRequest(request *pendingRequest) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, sequence := range request.sequences {
+		delete(c.pending, sequence)
+	}
+}
+
+func (c *Conn) lookupRequest(sequence Sequence) *pendingRequest {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.pending[sequence]
+}
+
+// receiveBroadcastResponses passes the responses received for a broadcast request to the given handler. A device
+// counts towards the receive device limit, as soon as it has answered all parts of the request.
+func (c *Conn) receiveBroadcastResponses(ctx context.Context, request *pendingRequest, timeout *time.Timer, handler func(*receiveQueueEntry) bool) error {
+	answered := make(map[string]map[Sequence]bool, 0)
+	devices := 0
 	for {
-		received := <-receiveQueue
+		received, err := request.next(ctx, timeout.C)
+		if err != nil {
+			return err
+		}
+		if received == nil {
+			return nil
+		}
 		if received.err != nil {
-			if !isTimeoutErr(received.err) {
-				return nil, received.err
+			return received.err
+		}
+		if !handler(received) {
+			return nil
+		}
+		device := received.msg.Header.DeviceAddress.String()
+		if answered[device] == nil {
+			answered[device] = make(map[Sequence]bool, len(request.sequences))
+		}
+		if !answered[device][received.msg.Header.Sequence] {
+			answered[device][received.msg.Header.Sequence] = true
+			if len(answered[device]) == len(request.sequences) {
+				devices++
 			}
-			break
 		}
-		receivedMsgs[received.msg.Header.DeviceAddress.String()] = received.msg
-		if 0 < c.ReceiveDeviceLimit && c.ReceiveDeviceLimit <= uint(len(receivedMsgs)) {
-			break
+		if 0 < c.ReceiveDeviceLimit && c.ReceiveDeviceLimit <= uint(devices) {
+			return nil
 		}
 	}
-	return receivedMsgs, nil
 }
 
-func (c *Conn) sendReceiveUnicastMessage(msg *Message) (map[string]*Message, error) {
-	receiveQueue := make(chan *receiveQueueEntry, 1)
-	go func() {
-		for {
-			msg, err := c.receiveMessage()
-			receiveQueue <- &receiveQueueEntry{
-				msg: msg,
-				err: err,
+// sendReceiveUnicastMessage sends a unicast request and waits for the response. Unanswered requests are retransmitted
+// according to the connection's retry policy (see RetryPolicy).
+func (c *Conn) sendReceiveUnicastMessage(ctx context.Context, msg *Message, request *pendingRequest, handler func(*receiveQueueEntry) bool) (bool, error) {
+	attempts := c.Retry.attempts(msg.Header.Operation)
+	attemptTimeout := c.Retry.attemptTimeout(c.ReceiveTimeout)
+	for attempt := uint(1); ; attempt++ {
+		err := c.sendMessage(msg, request.sequences[0])
+		if err != nil {
+			return false, err
+		}
+		wait := attemptTimeout
+		if attempt < attempts {
+			// Keep on waiting for a late response during the backoff
+			wait += c.Retry.backoff(attempt)
+		}
+		timeout := time.NewTimer(wait)
+		received, err := request.next(ctx, timeout.C)
+		timeout.Stop()
+		if err != nil {
+			return false, err
+		}
+		if received != nil {
+			if received.err != nil {
+				return false, received.err
 			}
-			break
+			received.msg.Attempts = attempt
+			return handler(received), nil
+		}
+		if attempt >= attempts {
+			return false, fmt.Errorf("no response received after %d attempt(s); cause: %w", attempt, os.ErrDeadlineExceeded)
+		}
+		if c.Debug {
+			log.Printf("NSDP %s retransmitting unanswered request (sequence: %04xh, attempt: %d)", c.laddr, request.sequences[0], attempt+1)
 		}
-	}()
-	err := c.sendMessage(msg)
-	if err != nil {
-		return nil, err
-	}
-	receivedMsgs := make(map[string]*Message, 0)
-	received := <-receiveQueue
-	if received.err != nil {
-		return nil, received.err
 	}
-	receivedMsgs[received.msg.Header.DeviceAddress.String()] = received.msg
-	return receivedMsgs, nil
 }
 
-func (c *Conn) sendMessage(msg *Message) error {
-	preparedMsg := msg.prepareMessage(c.host, c.seq)
+func (c *Conn) sendMessage(msg *Message, sequence Sequence) error {
+	preparedMsg := msg.prepareMessage(c.host, sequence)
 	sendBuffer := preparedMsg.Marshal()
 	if c.Debug {
-		log.Printf("NSDP %s > %s:\n%s\n%s", c.laddr, c.taddr, hex.EncodeToString(sendBuffer), preparedMsg)
+		log.Printf("NSDP %s > %s:\n%s\n%s", c.laddr, c.taddr, dumpMessage(sendBuffer, c.StrictDecoding), preparedMsg)
 	}
 	_, err := c.conn.WriteToUDP(sendBuffer, c.taddr)
-	return err
+	if err != nil {
+		return err
+	}
+	captureDatagram(c.Capture, c.Debug, c.laddr, c.taddr, sendBuffer)
+	return nil
 }
 
-func (c *Conn) receiveMessage() (*Message, error) {
-	buffer := make([]byte, c.ReceiveBufferSize)
+// Number of consecutive read errors after which the connection's receive goroutine gives up
+const maxConsecutiveReadErrors int = 8
+
+// Backoff applied after a read error (doubled for each consecutive read error)
+const minReadErrorBackoff time.Duration = 1 * time.Millisecond
+const maxReadErrorBackoff time.Duration = 100 * time.Millisecond
+
+// receiveLoop receives all datagrams of the connection and dispatches them to the pending requests until the
+// connection is closed. As the received messages are decoded into their own storage, a single receive buffer
+// is sufficient.
+//
+// Read errors are considered transient: they concern none of the pending requests (which keep on waiting for their
+// responses) and receiving is retried after a backoff. Only if reading fails repeatedly, the pending requests are
+// finished with the read error and the receive goroutine gives up.
+func (c *Conn) receiveLoop() {
+	defer close(c.receiverFinished)
+	var buffer []byte
+	readErrors := 0
+	backoff := minReadErrorBackoff
 	for {
-		len, addr, err := c.conn.ReadFromUDP(buffer)
-		if err != nil {
-			return nil, err
+		settings := c.loadReceiveSettings()
+		if uint(len(buffer)) < settings.bufferSize {
+			buffer = make([]byte, settings.bufferSize)
 		}
-		msg, err := c.unmarshalReceivedMessage(addr, buffer[:len])
+		len, addr, err := c.read(buffer)
 		if err != nil {
-			return nil, err
-		}
-		if !c.checkMessageSequence(addr, msg) {
+			c.mutex.Lock()
+			closed := c.closed
+			c.mutex.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				c.failPendingRequests(net.ErrClosed)
+				return
+			}
+			readErrors++
+			if settings.debug {
+				log.Printf("NSDP %s Error while receiving datagram (%d/%d); cause: %v", c.laddr, readErrors, maxConsecutiveReadErrors, err)
+			}
+			if readErrors >= maxConsecutiveReadErrors {
+				c.giveUpReceiving(fmt.Errorf("failed to receive after %d consecutive errors; cause: %w", readErrors, err))
+				return
+			}
+			time.Sleep(backoff)
+			backoff = min(2*backoff, maxReadErrorBackoff)
 			continue
 		}
-		if c.Debug {
-			log.Printf("NSDP %s < %s:\n%s\n%s", c.laddr, addr, hex.EncodeToString(buffer[:len]), msg)
+		readErrors = 0
+		backoff = minReadErrorBackoff
+		captureDatagram(settings.capture, settings.debug, addr, c.laddr, buffer[:len])
+		c.dispatchDatagram(settings, addr, buffer[:len])
+	}
+}
+
+func (c *Conn) loadReceiveSettings() receiveSettings {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.receiveSettings
+}
+
+// giveUpReceiving finishes all pending requests with the given error and rejects any further request.
+func (c *Conn) giveUpReceiving(err error) {
+	c.mutex.Lock()
+	c.receiveErr = err
+	c.mutex.Unlock()
+	c.failPendingRequests(err)
+}
+
+func (c *Conn) dispatchDatagram(settings receiveSettings, addr *net.UDPAddr, received []byte) {
+	msg, err := c.unmarshalReceivedMessage(settings, addr, received)
+	var sequence Sequence
+	if err == nil {
+		sequence = msg.Header.Sequence
+	} else {
+		// Report decoding errors to the request, the message has been sent for (if the header is intact)
+		header := &Header{}
+		if header.unmarshalBinary(received) != nil {
+			return
 		}
-		return msg, nil
+		sequence = header.Sequence
 	}
+	request := c.lookupRequest(sequence)
+	if request == nil {
+		if settings.debug {
+			log.Printf("NSDP %s < %s:\nIgnoring unsolicited message (sequence: %04xh)", c.laddr, addr, sequence)
+		}
+		return
+	}
+	if err == nil && settings.debug {
+		log.Printf("NSDP %s < %s:\n%s\n%s", c.laddr, addr, dumpMessage(received, settings.strictDecoding), msg)
+	}
+	request.queue(&receiveQueueEntry{msg: msg, digest: sha256.Sum256(received), err: err})
 }
 
-func (c *Conn) unmarshalReceivedMessage(addr *net.UDPAddr, received []byte) (*Message, error) {
-	msg, err := UnmarshalMessage(received)
+func (c *Conn) failPendingRequests(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, request := range c.pending {
+		request.queue(&receiveQueueEntry{err: err})
+	}
+}
+
+func (c *Conn) unmarshalReceivedMessage(settings receiveSettings, addr *net.UDPAddr, received []byte) (*Message, error) {
+	var msg *Message
+	var err error
+	if settings.strictDecoding {
+		msg, err = UnmarshalMessageStrict(received)
+	} else {
+		msg, err = UnmarshalMessage(received)
+	}
 	if err != nil {
-		if c.Debug {
-			log.Printf("NSDP %s < %s:\n%s", c.laddr, addr, hex.EncodeToString(received))
+		if settings.debug {
+			log.Printf("NSDP %s < %s:\n%s", c.laddr, addr, dumpMessage(received, settings.strictDecoding))
 			log.Printf("NSDP Error while unmarshaling message; cause: %v", err)
 		}
 		return nil, err
@@ -226,19 +640,14 @@ func (c *Conn) unmarshalReceivedMessage(addr *net.UDPAddr, received []byte) (*Me
 	return msg, nil
 }
 
-func (c *Conn) checkMessageSequence(addr *net.UDPAddr, msg *Message) bool {
-	if msg.Header.Sequence != c.seq {
-		if c.Debug {
-			log.Printf("NSDP %s < %s:\nIgnoring unsolicited message (sequence: %04xh)", c.laddr, addr, msg.Header.Sequence)
-		}
-		return false
+func captureDatagram(capture *CaptureWriter, debug bool, source *net.UDPAddr, destination *net.UDPAddr, data []byte) {
+	if capture == nil {
+		return
+	}
+	err := capture.WriteDatagram(time.Now(), source, destination, data)
+	if err != nil && debug {
+		log.Printf("NSDP Error while capturing datagram; cause: %v", err)
 	}
-	return true
-}
-
-func isTimeoutErr(err error) bool {
-	netErr, ok := err.(net.Error)
-	return ok && netErr.Timeout()
 }
 
 func lookupHardwareAddr(addr *net.UDPAddr) (net.HardwareAddr, error) {
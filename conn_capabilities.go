@@ -0,0 +1,222 @@
+// conn_capabilities.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+)
+
+// Capabilities lists the TLV types supported by a specific device model and firmware version.
+type Capabilities struct {
+	Model           string // Model name (e.g. GS108Ev3)
+	FirmwareVersion string // Firmware version (e.g. 2.06.17)
+	Supported       []Type // Supported TLV types (sorted)
+}
+
+// Supports checks whether the given TLV type is supported.
+func (capabilities *Capabilities) Supports(tlvType Type) bool {
+	_, found := slices.BinarySearch(capabilities.Supported, tlvType)
+	return found
+}
+
+// CapabilityCache caches probed capabilities by device model and firmware version.
+//
+// A CapabilityCache is safe for concurrent use and may be shared between multiple connections.
+type CapabilityCache struct {
+	mutex   sync.RWMutex
+	models  map[string]*Capabilities
+	devices map[string]*Capabilities
+}
+
+// NewCapabilityCache creates a new empty capability cache.
+func NewCapabilityCache() *CapabilityCache {
+	return &CapabilityCache{
+		models:  make(map[string]*Capabilities),
+		devices: make(map[string]*Capabilities),
+	}
+}
+
+// Lookup gets the cached capabilities for the given model and firmware version.
+func (cache *CapabilityCache) Lookup(model string, firmwareVersion string) (*Capabilities, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	capabilities, found := cache.models[capabilityKey(model, firmwareVersion)]
+	return capabilities, found
+}
+
+// LookupDevice gets the cached capabilities for the device identified by the given hardware address.
+//
+// Devices are only known to the cache after they have been probed (see Conn.ProbeCapabilities).
+func (cache *CapabilityCache) LookupDevice(device net.HardwareAddr) (*Capabilities, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	capabilities, found := cache.devices[device.String()]
+	return capabilities, found
+}
+
+// Store adds the given capabilities to the cache.
+func (cache *CapabilityCache) Store(capabilities *Capabilities) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.models[capabilityKey(capabilities.Model, capabilities.FirmwareVersion)] = capabilities
+}
+
+func (cache *CapabilityCache) storeDevice(device net.HardwareAddr, capabilities *Capabilities) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.devices[device.String()] = capabilities
+}
+
+func capabilityKey(model string, firmwareVersion string) string {
+	return model + "/" + firmwareVersion
+}
+
+// TLV types excluded from probing (as they are not readable)
+var nonProbeTypes = []Type{
+	TypePassword,
+}
+
+// ProbeCapabilities determines the TLV types supported by the device identified by the given hardware address.
+//
+// The device's model and firmware version are queried first. If the connection's capability cache already
+// contains an entry for this combination, the cached capabilities are used. Otherwise the device is queried
+// for all registered TLV types (see RegisterTLV) and the result is added to the cache. If the device rejects the combined
+// request, each TLV type is probed separately (unless the device reports the offending TLV type, which is then
+// excluded from the combined request). Only TLV types rejected by the device are considered unsupported. If probing
+// fails for any other reason (e.g. a timeout), an error is returned and the cache is left unchanged.
+func (c *Conn) ProbeCapabilities(device net.HardwareAddr) (*Capabilities, error) {
+	return c.ProbeCapabilitiesContext(context.Background(), device)
+}
+
+// ProbeCapabilitiesContext determines the TLV types supported by the device identified by the given hardware address
+// (see ProbeCapabilities). The probing is bounded by the given context (see SendReceiveMessageContext).
+func (c *Conn) ProbeCapabilitiesContext(ctx context.Context, device net.HardwareAddr) (*Capabilities, error) {
+	model, firmwareVersion, err := c.readModelAndFirmwareVersion(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	cache := c.Capabilities
+	if cache == nil {
+		cache = NewCapabilityCache()
+	}
+	capabilities, found := cache.Lookup(model, firmwareVersion)
+	if !found {
+		supported, err := c.probeTypes(ctx, device)
+		if err != nil {
+			return nil, fmt.Errorf("failed to probe capabilities of device %s; cause: %w", device, err)
+		}
+		capabilities = &Capabilities{
+			Model:           model,
+			FirmwareVersion: firmwareVersion,
+			Supported:       supported,
+		}
+		cache.Store(capabilities)
+	}
+	cache.storeDevice(device, capabilities)
+	return capabilities, nil
+}
+
+func (c *Conn) readModelAndFirmwareVersion(ctx context.Context, device net.HardwareAddr) (string, string, error) {
+	readMsg := NewMessage(ReadRequest)
+	readMsg.Header.DeviceAddress = device
+	readMsg.AppendTLV(EmptyDeviceModel())
+	readMsg.AppendTLV(EmptyFWVersionSlot1())
+	readResponses, err := c.SendReceiveMessageContext(ctx, readMsg)
+	if err != nil {
+		return "", "", err
+	}
+	for _, readResponse := range readResponses {
+		model, modelFound := First[*DeviceModel](readResponse)
+		firmwareVersion, firmwareVersionFound := First[*FWVersionSlot1](readResponse)
+		if modelFound && firmwareVersionFound {
+			return model.Model, firmwareVersion.Version, nil
+		}
+	}
+	return "", "", fmt.Errorf("device %s did not report its model and firmware version", device)
+}
+
+// probeTypes determines the TLV types supported by the given device. A TLV type is only considered unsupported, if
+// the device reports an error for it. Any other error (e.g. a timeout) aborts the probing, as the result would be
+// incomplete.
+func (c *Conn) probeTypes(ctx context.Context, device net.HardwareAddr) ([]Type, error) {
+	readMsg := NewMessage(ReadRequest)
+	readMsg.Header.DeviceAddress = device
+	for _, probeType := range RegisteredTLVTypes() {
+		if !slices.Contains(nonProbeTypes, probeType) {
+			readMsg.AppendTLV(newEmptyTLV(probeType))
+		}
+	}
+	supported, err := c.probeRequest(ctx, readMsg)
+	for err != nil {
+		var resultErr *ResultError
+		if !errors.As(err, &resultErr) {
+			return nil, err
+		}
+		// Retry without the offending TLV, if the device reports it
+		if resultErr.TLV == 0 || len(readMsg.Body) <= 1 {
+			break
+		}
+		remaining := slices.DeleteFunc(readMsg.Body, func(tlv TLV) bool { return tlv.Type() == resultErr.TLV })
+		if len(remaining) == len(readMsg.Body) {
+			break
+		}
+		readMsg.Body = remaining
+		supported, err = c.probeRequest(ctx, readMsg)
+	}
+	if err != nil {
+		supported = make([]Type, 0, len(readMsg.Body))
+		for _, tlv := range readMsg.Body {
+			probeMsg := NewMessage(ReadRequest)
+			probeMsg.Header.DeviceAddress = device
+			probeMsg.AppendTLV(tlv)
+			probed, err := c.probeRequest(ctx, probeMsg)
+			var resultErr *ResultError
+			if err != nil && !errors.As(err, &resultErr) {
+				return nil, err
+			}
+			supported = append(supported, probed...)
+		}
+	}
+	slices.Sort(supported)
+	return slices.Compact(supported), nil
+}
+
+func (c *Conn) probeRequest(ctx context.Context, msg *Message) ([]Type, error) {
+	responses, err := c.SendReceiveMessageContext(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	supported := make([]Type, 0, len(msg.Body))
+	for _, response := range responses {
+		for _, tlv := range response.Body {
+			supported = append(supported, tlv.Type())
+		}
+	}
+	return supported, nil
+}
+
+func (c *Conn) checkCapabilities(device net.HardwareAddr, tlvs ...TLV) error {
+	if c.Capabilities == nil {
+		return nil
+	}
+	capabilities, found := c.Capabilities.LookupDevice(device)
+	if !found {
+		return nil
+	}
+	for _, tlv := range tlvs {
+		if !capabilities.Supports(tlv.Type()) {
+			return fmt.Errorf("device %s (%s %s) does not support TLV type %04xh", device, capabilities.Model, capabilities.FirmwareVersion, tlv.Type())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// conn_capabilities_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestConnProbeCapabilities(t *testing.T) {
+	device1 := getStaticMAC()
+	device2 := net.HardwareAddr{0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestModelResponse(device1))
+	probeResponse := nsdp.NewMessage(nsdp.ReadResponse)
+	probeResponse.Header.DeviceAddress = device1
+	probeResponse.AppendTLV(nsdp.NewDeviceModel("GS108Ev3"))
+	probeResponse.AppendTLV(nsdp.NewDeviceName("switch1"))
+	probeResponse.AppendTLV(nsdp.NewDeviceIP(net.IP{10, 1, 0, 4}))
+	probeResponse.AppendTLV(nsdp.NewFWVersionSlot1("2.06.17"))
+	probeResponse.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link1GFull))
+	probeResponse.AppendTLV(nsdp.NewPortStatus(2, nsdp.LinkDown))
+	responder.AddResponses(hex.EncodeToString(probeResponse.Marshal()))
+	responder.AddResponses(newTestModelResponse(device2))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	capabilities1, err := conn.ProbeCapabilities(device1)
+	require.NoError(t, err)
+	require.Equal(t, "GS108Ev3", capabilities1.Model)
+	require.Equal(t, "2.06.17", capabilities1.FirmwareVersion)
+	require.Equal(t, []nsdp.Type{nsdp.TypeDeviceModel, nsdp.TypeDeviceName, nsdp.TypeDeviceIP, nsdp.TypeFWVersionSlot1, nsdp.TypePortStatus}, capabilities1.Supported)
+	require.True(t, capabilities1.Supports(nsdp.TypeDeviceIP))
+	require.False(t, capabilities1.Supports(nsdp.TypeDHCPMode))
+	capabilities2, err := conn.ProbeCapabilities(device2)
+	require.NoError(t, err)
+	require.Same(t, capabilities1, capabilities2)
+	_, err = conn.RenewDHCP(device2, "password")
+	require.Error(t, err)
+}
+
+func TestConnProbeCapabilitiesTimeout(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestModelResponse(device))
+	// Probe request is lost
+	responder.AddResponses()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveTimeout = 200 * time.Millisecond
+	_, err = conn.ProbeCapabilities(device)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	_, found := conn.Capabilities.Lookup("GS108Ev3", "2.06.17")
+	require.False(t, found)
+}
+
+func TestConnProbeCapabilitiesErrorTLV(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestModelResponse(device))
+	// The device rejects two different TLVs one after the other
+	responder.AddResponses(newTestProbeFailure(device, nsdp.TypePowerSaving))
+	responder.AddResponses(newTestProbeFailure(device, nsdp.TypeLEDControl))
+	responder.AddResponses(newTestProbeResponse(device, nsdp.TypeDeviceModel, nsdp.TypeDeviceIP))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 0
+	buffer := &bytes.Buffer{}
+	conn.Capture, err = nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	capabilities, err := conn.ProbeCapabilities(device)
+	require.NoError(t, err)
+	require.Equal(t, []nsdp.Type{nsdp.TypeDeviceModel, nsdp.TypeDeviceIP}, capabilities.Supported)
+	probeRequests := readTestProbeRequests(t, buffer)
+	require.Equal(t, 3, len(probeRequests))
+	require.Contains(t, probeRequests[0], nsdp.TypePowerSaving)
+	require.Contains(t, probeRequests[0], nsdp.TypeLEDControl)
+	require.NotContains(t, probeRequests[1], nsdp.TypePowerSaving)
+	require.Contains(t, probeRequests[1], nsdp.TypeLEDControl)
+	require.NotContains(t, probeRequests[2], nsdp.TypePowerSaving)
+	require.NotContains(t, probeRequests[2], nsdp.TypeLEDControl)
+	require.Equal(t, len(probeRequests[0])-2, len(probeRequests[2]))
+}
+
+func TestConnProbeCapabilitiesPerType(t *testing.T) {
+	device := getStaticMAC()
+	probeTypes := slices.DeleteFunc(nsdp.RegisteredTLVTypes(), func(tlvType nsdp.Type) bool { return tlvType == nsdp.TypePassword })
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestModelResponse(device))
+	// The device rejects the combined request without reporting the offending TLV
+	responder.AddResponses(newTestProbeFailure(device, 0))
+	for _, probeType := range probeTypes {
+		if probeType == nsdp.TypePowerSaving || probeType == nsdp.TypeLEDControl {
+			responder.AddResponses(newTestProbeFailure(device, 0))
+		} else {
+			responder.AddResponses(newTestProbeResponse(device, probeType))
+		}
+	}
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 0
+	buffer := &bytes.Buffer{}
+	conn.Capture, err = nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	capabilities, err := conn.ProbeCapabilities(device)
+	require.NoError(t, err)
+	expected := slices.DeleteFunc(slices.Clone(probeTypes), func(tlvType nsdp.Type) bool {
+		return tlvType == nsdp.TypePowerSaving || tlvType == nsdp.TypeLEDControl
+	})
+	slices.Sort(expected)
+	require.Equal(t, expected, capabilities.Supported)
+	probeRequests := readTestProbeRequests(t, buffer)
+	require.Equal(t, 1+len(probeTypes), len(probeRequests))
+	for i, probeType := range probeTypes {
+		require.Equal(t, []nsdp.Type{probeType}, probeRequests[1+i])
+	}
+}
+
+func newTestProbeFailure(device net.HardwareAddr, errorTLV nsdp.Type) string {
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.Header.DeviceAddress = device
+	response.Header.Result = nsdp.ResultInvalidValue
+	response.Header.SetErrorTLV(errorTLV)
+	return hex.EncodeToString(response.Marshal())
+}
+
+func newTestProbeResponse(device net.HardwareAddr, tlvTypes ...nsdp.Type) string {
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.Header.DeviceAddress = device
+	for _, tlvType := range tlvTypes {
+		response.AppendTLV(nsdp.NewRawTLV(tlvType, nil))
+	}
+	return hex.EncodeToString(response.Marshal())
+}
+
+// readTestProbeRequests gets the TLV types of the captured probe requests (skipping the model request).
+func readTestProbeRequests(t *testing.T, buffer *bytes.Buffer) [][]nsdp.Type {
+	reader, err := nsdp.NewCaptureReader(buffer)
+	require.NoError(t, err)
+	reader.Ports = nil
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	probeRequests := make([][]nsdp.Type, 0)
+	for _, datagram := range datagrams {
+		if datagram.Message.Header.Operation != nsdp.ReadRequest {
+			continue
+		}
+		tlvTypes := make([]nsdp.Type, 0, len(datagram.Message.Body))
+		for _, tlv := range datagram.Message.Body {
+			tlvTypes = append(tlvTypes, tlv.Type())
+		}
+		probeRequests = append(probeRequests, tlvTypes)
+	}
+	return probeRequests[1:]
+}
+
+func newTestModelResponse(device net.HardwareAddr) string {
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.Header.DeviceAddress = device
+	response.AppendTLV(nsdp.NewDeviceModel("GS108Ev3"))
+	response.AppendTLV(nsdp.NewFWVersionSlot1("2.06.17"))
+	return hex.EncodeToString(response.Marshal())
+}
+
+func TestConnProbeCapabilitiesContextCancelled(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = conn.ProbeCapabilitiesContext(ctx, getStaticMAC())
+	require.ErrorIs(t, err, context.Canceled)
+}
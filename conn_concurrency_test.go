@@ -0,0 +1,144 @@
+// conn_concurrency_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+const concurrencyTestRequests int = 16
+
+func TestConnConcurrentUnicast(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	devices := make(map[string]bool, concurrencyTestRequests)
+	for i := range concurrencyTestRequests {
+		device := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, byte(i)}
+		devices[device.String()] = true
+		responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceName(device.String())))
+	}
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), false)
+	require.NoError(t, err)
+	defer conn.Close()
+	var wg sync.WaitGroup
+	results := make(chan map[string]*nsdp.Message, concurrencyTestRequests)
+	errs := make(chan error, concurrencyTestRequests)
+	for range concurrencyTestRequests {
+		wg.Go(func() {
+			msg := nsdp.NewMessage(nsdp.ReadRequest)
+			msg.Header.DeviceAddress = getStaticMAC()
+			msg.AppendTLV(nsdp.EmptyDeviceName())
+			responses, err := conn.SendReceiveMessage(msg)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- responses
+		})
+	}
+	wg.Wait()
+	close(results)
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	// Each response must be delivered to exactly one request
+	received := make(map[string]bool, concurrencyTestRequests)
+	for responses := range results {
+		require.Equal(t, 1, len(responses))
+		for device, response := range responses {
+			require.False(t, received[device])
+			received[device] = true
+			name, found := nsdp.First[*nsdp.DeviceName](response)
+			require.True(t, found)
+			require.Equal(t, device, name.Name)
+		}
+	}
+	require.Equal(t, devices, received)
+}
+
+func TestConnConcurrentBroadcast(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	for range concurrencyTestRequests {
+		responder.AddResponses(connTestResponseSwitch1, connTestResponseSwitch2)
+	}
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), false)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveDeviceLimit = 2
+	var wg sync.WaitGroup
+	counts := make(chan int, concurrencyTestRequests)
+	for range concurrencyTestRequests {
+		wg.Go(func() {
+			responses, err := conn.SendReceiveMessage(prepareTestMessage())
+			if err != nil {
+				counts <- -1
+				return
+			}
+			counts <- len(responses)
+		})
+	}
+	wg.Wait()
+	close(counts)
+	for count := range counts {
+		require.Equal(t, 2, count)
+	}
+}
+
+func TestConnConcurrentDeadlines(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceName("switch1")))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), false)
+	require.NoError(t, err)
+	defer conn.Close()
+	// The first request is answered; the second one is not answered and bounded by its own deadline only
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.Header.DeviceAddress = device
+	msg.AppendTLV(nsdp.EmptyDeviceName())
+	_, err = conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	conn.ReceiveTimeout = 10 * time.Second
+	longCtx, longCancel := context.WithCancel(context.Background())
+	longResult := make(chan error, 1)
+	go func() {
+		_, err := conn.SendReceiveMessageContext(longCtx, msg)
+		longResult <- err
+	}()
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shortCancel()
+	start := time.Now()
+	_, err = conn.SendReceiveMessageContext(shortCtx, msg)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+	select {
+	case <-longResult:
+		require.Fail(t, "long running request finished by other request's deadline")
+	default:
+	}
+	longCancel()
+	require.ErrorIs(t, <-longResult, context.Canceled)
+}
@@ -0,0 +1,235 @@
+// conn_ip_config.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// IPConfig defines a device's IP configuration as applied by Conn.ReconfigureIP.
+type IPConfig struct {
+	DHCP    bool   // Enables DHCP (if set, IP, Netmask and Router are ignored)
+	IP      net.IP // Device IP
+	Netmask net.IP // Device netmask
+	Router  net.IP // Gateway address
+}
+
+// Validate checks whether the configuration is consistent.
+//
+// For a static configuration, IP, netmask and gateway must be valid IPv4 addresses, the netmask must be contiguous,
+// and the gateway must be located inside the subnet defined by IP and netmask.
+func (config *IPConfig) Validate() error {
+	if config.DHCP {
+		return nil
+	}
+	ip := config.IP.To4()
+	if ip == nil || ip.IsUnspecified() {
+		return fmt.Errorf("invalid device IP: %s", config.IP)
+	}
+	netmask := config.Netmask.To4()
+	if netmask == nil {
+		return fmt.Errorf("invalid device netmask: %s", config.Netmask)
+	}
+	ones, bits := net.IPMask(netmask).Size()
+	if bits == 0 || ones == 0 {
+		return fmt.Errorf("non-contiguous device netmask: %s", config.Netmask)
+	}
+	router := config.Router.To4()
+	if router == nil || router.IsUnspecified() {
+		return fmt.Errorf("invalid gateway address: %s", config.Router)
+	}
+	subnet := &net.IPNet{IP: ip.Mask(net.IPMask(netmask)), Mask: net.IPMask(netmask)}
+	if !subnet.Contains(router) {
+		return fmt.Errorf("gateway address %s outside of subnet %s", config.Router, subnet)
+	}
+	if router.Equal(ip) {
+		return fmt.Errorf("gateway address %s equals device IP", config.Router)
+	}
+	return nil
+}
+
+func (config *IPConfig) tlvs() []TLV {
+	if config.DHCP {
+		return []TLV{NewDHCPMode(DHCPEnabled)}
+	}
+	return []TLV{
+		NewDeviceIP(config.IP.To4()),
+		NewDeviceNetmask(config.Netmask.To4()),
+		NewRouterIP(config.Router.To4()),
+		NewDHCPMode(DHCPDisabled),
+	}
+}
+
+// IPConfigResult reports the outcome of a Conn.ReconfigureIP call.
+type IPConfigResult struct {
+	Applied    []Type   // TLV types written and confirmed by the device
+	NotApplied []Type   // TLV types written, but not confirmed by the device
+	Response   *Message // Read response used for confirmation (nil if none has been received)
+}
+
+// Complete checks whether all written TLV types have been confirmed by the device.
+func (result *IPConfigResult) Complete() bool {
+	return len(result.NotApplied) == 0
+}
+
+// ReconfigureIP applies the given IP configuration to the device identified by the given hardware address.
+//
+// The configuration is validated first and then sent as a single write request (authorized by the given password).
+// Afterwards the device is queried via a read request addressed by its hardware address, to confirm that the new
+// values are in effect. The returned result lists the fields confirmed by the device. If the confirmation fails,
+// the result is returned together with the error.
+func (c *Conn) ReconfigureIP(device net.HardwareAddr, password string, config *IPConfig) (*IPConfigResult, error) {
+	return c.ReconfigureIPContext(context.Background(), device, password, config)
+}
+
+// ReconfigureIPContext applies the given IP configuration to the device identified by the given hardware address
+// (see ReconfigureIP). The reconfiguration is bounded by the given context (see SendReceiveMessageContext).
+func (c *Conn) ReconfigureIPContext(ctx context.Context, device net.HardwareAddr, password string, config *IPConfig) (*IPConfigResult, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	tlvs := config.tlvs()
+	err = c.checkCapabilities(device, tlvs...)
+	if err != nil {
+		return nil, err
+	}
+	err = c.writeDevice(ctx, device, password, tlvs...)
+	if err != nil {
+		return nil, err
+	}
+	result := &IPConfigResult{
+		Applied:    make([]Type, 0, len(tlvs)),
+		NotApplied: make([]Type, 0, len(tlvs)),
+	}
+	readMsg := NewMessage(ReadRequest)
+	readMsg.Header.DeviceAddress = device
+	for _, tlv := range tlvs {
+		readMsg.AppendTLV(newEmptyTLV(tlv.Type()))
+	}
+	readResponses, err := c.SendReceiveMessageContext(ctx, readMsg)
+	if err != nil {
+		for _, tlv := range tlvs {
+			result.NotApplied = append(result.NotApplied, tlv.Type())
+		}
+		return result, fmt.Errorf("failed to confirm IP configuration of device %s; cause: %w", device, err)
+	}
+	for _, readResponse := range readResponses {
+		result.Response = readResponse
+	}
+	for _, tlv := range tlvs {
+		if result.Response != nil && isTLVConfirmed(result.Response, tlv) {
+			result.Applied = append(result.Applied, tlv.Type())
+		} else {
+			result.NotApplied = append(result.NotApplied, tlv.Type())
+		}
+	}
+	return result, nil
+}
+
+// RenewDHCP triggers a DHCP lease renew on the device identified by the given hardware address.
+//
+// Before sending the renew request (authorized by the given password), the device's current IP address is recorded.
+// Afterwards the device is polled until it reports a different valid IP address, or until it reports a valid IP
+// address after having reported an unspecified one (the lease has been dropped and acquired again). If the device
+// keeps reporting the recorded IP address until the DHCP renew timeout is reached, the DHCP server is assumed to have
+// handed out the same lease again and the unchanged IP address is returned. Receiving no valid IP address at all
+// after the renew is considered an error.
+func (c *Conn) RenewDHCP(device net.HardwareAddr, password string) (net.IP, error) {
+	return c.RenewDHCPContext(context.Background(), device, password)
+}
+
+// RenewDHCPContext triggers a DHCP lease renew on the device identified by the given hardware address (see RenewDHCP).
+// The renew (including the polling for the IP address) is bounded by the given context.
+func (c *Conn) RenewDHCPContext(ctx context.Context, device net.HardwareAddr, password string) (net.IP, error) {
+	err := c.checkCapabilities(device, EmptyDHCPMode(), EmptyDeviceIP())
+	if err != nil {
+		return nil, err
+	}
+	previousIP, err := c.readDeviceIP(ctx, device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IP of device %s before DHCP renew; cause: %w", device, err)
+	}
+	err = c.writeDevice(ctx, device, password, NewDHCPMode(DHCPRenew))
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(c.DHCPRenewTimeout)
+	released := false
+	var lastIP net.IP
+	for {
+		ip, err := c.readDeviceIP(ctx, device)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil && len(ip) > 0 {
+			if ip.IsUnspecified() {
+				released = true
+				lastIP = nil
+			} else if released || !ip.Equal(previousIP) {
+				return ip, nil
+			} else {
+				lastIP = ip
+			}
+		}
+		if !time.Now().Before(deadline) {
+			if lastIP != nil {
+				return lastIP, nil
+			}
+			return nil, fmt.Errorf("device %s did not report its IP after DHCP renew", device)
+		}
+		select {
+		case <-time.After(dhcpRenewPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+const dhcpRenewPollInterval time.Duration = 500 * time.Millisecond
+
+func (c *Conn) writeDevice(ctx context.Context, device net.HardwareAddr, password string, tlvs ...TLV) error {
+	writeMsg := NewMessage(WriteRequest)
+	writeMsg.Header.DeviceAddress = device
+	writeMsg.AppendTLV(NewRawTLV(TypePassword, []byte(password)))
+	for _, tlv := range tlvs {
+		writeMsg.AppendTLV(tlv)
+	}
+	_, err := c.SendReceiveMessageContext(ctx, writeMsg)
+	return err
+}
+
+func (c *Conn) readDeviceIP(ctx context.Context, device net.HardwareAddr) (net.IP, error) {
+	readMsg := NewMessage(ReadRequest)
+	readMsg.Header.DeviceAddress = device
+	readMsg.AppendTLV(EmptyDeviceIP())
+	readResponses, err := c.SendReceiveMessageContext(ctx, readMsg)
+	if err != nil {
+		return nil, err
+	}
+	for _, readResponse := range readResponses {
+		deviceIP, found := First[*DeviceIP](readResponse)
+		if found {
+			return deviceIP.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("device %s did not report its IP", device)
+}
+
+func isTLVConfirmed(msg *Message, written TLV) bool {
+	for _, tlv := range msg.Body {
+		if tlv.Type() == written.Type() {
+			return bytes.Equal(tlv.Value(), written.Value())
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+// conn_ip_config_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestIPConfigValidate(t *testing.T) {
+	require.NoError(t, (&nsdp.IPConfig{DHCP: true}).Validate())
+	require.NoError(t, newTestIPConfig("10.1.0.4", "255.255.0.0", "10.1.0.1").Validate())
+	require.Error(t, newTestIPConfig("10.1.0.4", "255.0.255.0", "10.1.0.1").Validate())
+	require.Error(t, newTestIPConfig("10.1.0.4", "0.0.0.0", "10.1.0.1").Validate())
+	require.Error(t, newTestIPConfig("10.1.0.4", "255.255.255.0", "10.2.0.1").Validate())
+	require.Error(t, newTestIPConfig("10.1.0.4", "255.255.255.0", "10.1.0.4").Validate())
+	require.Error(t, newTestIPConfig("0.0.0.0", "255.255.255.0", "10.1.0.1").Validate())
+	require.Error(t, newTestIPConfig("fe80::1", "255.255.255.0", "10.1.0.1").Validate())
+}
+
+func TestConnReconfigureIP(t *testing.T) {
+	device := getStaticMAC()
+	config := newTestIPConfig("10.1.0.4", "255.255.0.0", "10.1.0.1")
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	writeResponse := nsdp.NewMessage(nsdp.WriteResponse)
+	writeResponse.Header.DeviceAddress = device
+	readResponse := nsdp.NewMessage(nsdp.ReadResponse)
+	readResponse.Header.DeviceAddress = device
+	readResponse.AppendTLV(nsdp.NewDeviceIP(config.IP.To4()))
+	readResponse.AppendTLV(nsdp.NewDeviceNetmask(net.IP{0xff, 0xff, 0xff, 0x00}))
+	readResponse.AppendTLV(nsdp.NewRouterIP(config.Router.To4()))
+	readResponse.AppendTLV(nsdp.NewDHCPMode(nsdp.DHCPDisabled))
+	responder.AddResponses(hex.EncodeToString(writeResponse.Marshal()))
+	responder.AddResponses(hex.EncodeToString(readResponse.Marshal()))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	result, err := conn.ReconfigureIP(device, "password", config)
+	require.NoError(t, err)
+	require.False(t, result.Complete())
+	require.Equal(t, []nsdp.Type{nsdp.TypeDeviceIP, nsdp.TypeRouterIP, nsdp.TypeDHCPMode}, result.Applied)
+	require.Equal(t, []nsdp.Type{nsdp.TypeDeviceNetmask}, result.NotApplied)
+}
+
+func TestConnRenewDHCP(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 4}))
+	responder.AddResponses(newTestWriteResponse(device))
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{0, 0, 0, 0}))
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 4}))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	start := time.Now()
+	ip, err := conn.RenewDHCP(device, "password")
+	require.NoError(t, err)
+	require.Equal(t, net.IP{10, 1, 0, 4}, ip)
+	require.Less(t, time.Since(start), conn.DHCPRenewTimeout)
+}
+
+func TestConnRenewDHCPChangedLease(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 4}))
+	responder.AddResponses(newTestWriteResponse(device))
+	// 1st poll still reports the old lease
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 4}))
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 5}))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	start := time.Now()
+	ip, err := conn.RenewDHCP(device, "password")
+	require.NoError(t, err)
+	require.Equal(t, net.IP{10, 1, 0, 5}, ip)
+	require.Less(t, time.Since(start), conn.DHCPRenewTimeout)
+}
+
+func TestConnRenewDHCPSameLease(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 4}))
+	responder.AddResponses(newTestWriteResponse(device))
+	for range 4 {
+		responder.AddResponses(newTestDeviceIPResponse(device, net.IP{10, 1, 0, 4}))
+	}
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DHCPRenewTimeout = 1 * time.Second
+	start := time.Now()
+	ip, err := conn.RenewDHCP(device, "password")
+	require.NoError(t, err)
+	require.Equal(t, net.IP{10, 1, 0, 4}, ip)
+	require.GreaterOrEqual(t, time.Since(start), conn.DHCPRenewTimeout)
+}
+
+func newTestWriteResponse(device net.HardwareAddr) string {
+	writeResponse := nsdp.NewMessage(nsdp.WriteResponse)
+	writeResponse.Header.DeviceAddress = device
+	return hex.EncodeToString(writeResponse.Marshal())
+}
+
+func newTestDeviceIPResponse(device net.HardwareAddr, ip net.IP) string {
+	readResponse := nsdp.NewMessage(nsdp.ReadResponse)
+	readResponse.Header.DeviceAddress = device
+	readResponse.AppendTLV(nsdp.NewDeviceIP(ip))
+	return hex.EncodeToString(readResponse.Marshal())
+}
+
+func TestDHCPModeValidate(t *testing.T) {
+	msg := nsdp.NewMessage(nsdp.WriteRequest)
+	msg.AppendTLV(nsdp.NewDHCPMode(nsdp.DHCPRenew))
+	require.NoError(t, msg.Validate())
+	msg.AppendTLV(nsdp.NewDHCPMode(0x03))
+	require.Error(t, msg.Validate())
+}
+
+func newTestIPConfig(ip, netmask, router string) *nsdp.IPConfig {
+	return &nsdp.IPConfig{
+		IP:      net.ParseIP(ip),
+		Netmask: net.ParseIP(netmask),
+		Router:  net.ParseIP(router),
+	}
+}
@@ -0,0 +1,75 @@
+// conn_retry.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy defines how unanswered unicast requests are retransmitted (see Conn.Retry).
+//
+// Retransmissions reuse the sequence number of the initial transmission. Hence a late response to an earlier
+// transmission is accepted as well. The delay before each retransmission starts with Backoff and is doubled for
+// each further retransmission (bounded by MaxBackoff). The delay is randomly varied by the Jitter fraction, to avoid
+// synchronized retransmissions of concurrent requests. Responses are also accepted while waiting for the delay.
+type RetryPolicy struct {
+	Attempts       uint          // Maximum number of transmissions including the initial one (0 or 1 disables retransmission)
+	AttemptTimeout time.Duration // Time to wait for a response per transmission (0 for the connection's receive timeout)
+	Backoff        time.Duration // Delay before the 1st retransmission
+	MaxBackoff     time.Duration // Upper bound for the delay (0 for no bound)
+	Jitter         float64       // Random variation of the delay as a fraction of the delay (e.g. 0.2 for +/-20%)
+	RetryWrites    bool          // Retransmits write requests as well (write requests may not be idempotent)
+}
+
+// NewRetryPolicy creates a new retry policy with the given number of attempts and sensible defaults for the
+// remaining settings (500ms per attempt, 100ms initial backoff up to 1s, 20% jitter).
+func NewRetryPolicy(attempts uint) *RetryPolicy {
+	return &RetryPolicy{
+		Attempts:       attempts,
+		AttemptTimeout: 500 * time.Millisecond,
+		Backoff:        100 * time.Millisecond,
+		MaxBackoff:     1000 * time.Millisecond,
+		Jitter:         0.2,
+	}
+}
+
+func (policy *RetryPolicy) attempts(operation OperationCode) uint {
+	if policy == nil || policy.Attempts == 0 || (operation == WriteRequest && !policy.RetryWrites) {
+		return 1
+	}
+	return policy.Attempts
+}
+
+func (policy *RetryPolicy) attemptTimeout(receiveTimeout time.Duration) time.Duration {
+	if policy == nil || policy.AttemptTimeout == 0 {
+		return receiveTimeout
+	}
+	return policy.AttemptTimeout
+}
+
+// backoff determines the delay before the given retransmission (starting with 1).
+func (policy *RetryPolicy) backoff(retransmission uint) time.Duration {
+	if policy == nil || policy.Backoff <= 0 {
+		return 0
+	}
+	backoff := policy.Backoff
+	for range retransmission - 1 {
+		if policy.MaxBackoff > 0 && backoff >= policy.MaxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if policy.MaxBackoff > 0 {
+		backoff = min(backoff, policy.MaxBackoff)
+	}
+	if policy.Jitter > 0 {
+		backoff += time.Duration((rand.Float64()*2 - 1) * policy.Jitter * float64(backoff))
+	}
+	return max(backoff, 0)
+}
@@ -0,0 +1,131 @@
+// conn_retry_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestConnRetryUnicast(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	// 1st transmission is lost
+	responder.AddResponses()
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	buffer := &bytes.Buffer{}
+	conn.Capture, err = nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	conn.Retry = newTestRetryPolicy(3)
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = getStaticMAC()
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	for _, response := range responses {
+		require.Equal(t, uint(2), response.Attempts)
+	}
+	reader, err := nsdp.NewCaptureReader(buffer)
+	require.NoError(t, err)
+	reader.Ports = nil
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(datagrams))
+	require.Equal(t, nsdp.ReadRequest, datagrams[0].Message.Header.Operation)
+	require.Equal(t, nsdp.ReadRequest, datagrams[1].Message.Header.Operation)
+	require.Equal(t, datagrams[0].Data, datagrams[1].Data)
+	require.Equal(t, nsdp.ReadResponse, datagrams[2].Message.Header.Operation)
+}
+
+func TestConnRetryWithoutLoss(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.Retry = newTestRetryPolicy(3)
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = getStaticMAC()
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	for _, response := range responses {
+		require.Equal(t, uint(1), response.Attempts)
+	}
+}
+
+func TestConnRetryExhausted(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses()
+	responder.AddResponses()
+	responder.AddResponses()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.Retry = newTestRetryPolicy(3)
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = getStaticMAC()
+	_, err = conn.SendReceiveMessage(msg)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.ErrorContains(t, err, "after 3 attempt(s)")
+}
+
+func TestConnRetryWriteRequest(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	buffer := &bytes.Buffer{}
+	conn.Capture, err = nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	conn.Retry = newTestRetryPolicy(3)
+	msg := nsdp.NewMessage(nsdp.WriteRequest)
+	msg.Header.DeviceAddress = getStaticMAC()
+	msg.AppendTLV(nsdp.NewDeviceName("switch"))
+	_, err = conn.SendReceiveMessage(msg)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.ErrorContains(t, err, "after 1 attempt(s)")
+	reader, err := nsdp.NewCaptureReader(buffer)
+	require.NoError(t, err)
+	reader.Ports = nil
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(datagrams))
+}
+
+func newTestRetryPolicy(attempts uint) *nsdp.RetryPolicy {
+	policy := nsdp.NewRetryPolicy(attempts)
+	policy.AttemptTimeout = 200 * time.Millisecond
+	policy.Backoff = 10 * time.Millisecond
+	policy.MaxBackoff = 50 * time.Millisecond
+	return policy
+}
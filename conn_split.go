@@ -0,0 +1,122 @@
+// conn_split.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"strings"
+)
+
+// Default response size budget (maximum UDP payload of a non-fragmented IPv4 datagram on Ethernet)
+const defaultDatagramBudget uint = 1472
+
+// Estimated response value lengths for TLV types of variable length
+var responseValueLengthEstimates = map[Type]int{
+	TypeDeviceModel:    32,
+	TypeDeviceName:     32,
+	TypeDeviceMAC:      6,
+	TypeDeviceLocation: 64,
+	TypeDeviceIP:       4,
+	TypeDeviceNetmask:  4,
+	TypeRouterIP:       4,
+	TypeFWVersionSlot1: 16,
+	TypeFWVersionSlot2: 16,
+}
+
+// Estimated response value length for TLV types of unknown length
+const defaultResponseValueLengthEstimate int = 64
+
+// Port count assumed for estimating the response length of per-port TLV types (devices with more ports require a
+// model specific budget; see DatagramBudgets)
+const portCountEstimate int = 8
+
+// datagramBudget determines the response size budget applicable for the given model (empty if unknown). Model specific
+// budgets are matched by model prefix (e.g. "GS108E" matches "GS108Ev3"); the longest matching prefix wins.
+func (c *Conn) datagramBudget(model string) uint {
+	if c.DatagramBudget == 0 {
+		return 0
+	}
+	budget := c.DatagramBudget
+	matchLength := -1
+	for prefix, prefixBudget := range c.DatagramBudgets {
+		if prefixBudget > 0 && len(prefix) > matchLength && model != "" && strings.HasPrefix(model, prefix) {
+			budget = prefixBudget
+			matchLength = len(prefix)
+		}
+	}
+	return budget
+}
+
+func (c *Conn) lookupModel(msg *Message) string {
+	if c.Capabilities == nil {
+		return ""
+	}
+	capabilities, found := c.Capabilities.LookupDevice(msg.Header.DeviceAddress)
+	if !found {
+		return ""
+	}
+	return capabilities.Model
+}
+
+// splitRequest splits the given read request into several requests, such that the estimated response size of each
+// request stays within the applicable datagram budget. Requests of other operations are never split, as this would
+// break their atomicity.
+func (c *Conn) splitRequest(msg *Message) []*Message {
+	if msg.Header.Operation != ReadRequest {
+		return []*Message{msg}
+	}
+	model := c.lookupModel(msg)
+	budget := int(c.datagramBudget(model))
+	if budget == 0 {
+		return []*Message{msg}
+	}
+	messageLength := headerLength + 4
+	parts := make([]*Message, 0, 1)
+	partLength := messageLength
+	partStart := 0
+	for i, tlv := range msg.Body {
+		tlvLength := estimateResponseLength(tlv, portCountEstimate)
+		if partLength+tlvLength > budget && i > partStart {
+			parts = append(parts, &Message{Header: msg.Header, Body: msg.Body[partStart:i:i], EOM: msg.EOM})
+			partLength = messageLength
+			partStart = i
+		}
+		partLength += tlvLength
+	}
+	if len(parts) == 0 {
+		return []*Message{msg}
+	}
+	return append(parts, &Message{Header: msg.Header, Body: msg.Body[partStart:len(msg.Body):len(msg.Body)], EOM: msg.EOM})
+}
+
+func estimateResponseLength(tlv TLV, ports int) int {
+	valueLength, found := responseValueLengthEstimates[tlv.Type()]
+	if !found {
+		valueLength = int(tlv.Length())
+		if valueLength == 0 {
+			valueLength = defaultResponseValueLengthEstimate
+		}
+	}
+	length := 4 + valueLength
+	_, perPort := tlv.(PortTLV)
+	if perPort {
+		length *= ports
+	}
+	return length
+}
+
+// mergeResponse merges a further response of the same device (either a further datagram or the response to a further
+// part of a split request) into the given response. The response bodies are concatenated. If the further response
+// reports a failure, its header is retained to report the failure.
+func mergeResponse(merged *Message, response *Message) {
+	merged.Body = append(merged.Body, response.Body...)
+	merged.Duplicates += response.Duplicates
+	merged.Attempts = max(merged.Attempts, response.Attempts)
+	if merged.Header.Result == ResultSuccess && response.Header.Result != ResultSuccess {
+		merged.Header = response.Header
+	}
+}
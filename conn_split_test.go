@@ -0,0 +1,181 @@
+// conn_split_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestConnSplitRequestUnicast(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceModel("GS108Ev3")))
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceName("switch1"), nsdp.NewDeviceIP(net.IP{10, 1, 0, 3})))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 100
+	conn.CheckCompleteness = true
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.Header.DeviceAddress = device
+	msg.AppendTLV(nsdp.EmptyDeviceModel())
+	msg.AppendTLV(nsdp.EmptyDeviceName())
+	msg.AppendTLV(nsdp.EmptyDeviceIP())
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	response := responses[device.String()]
+	require.Equal(t, []nsdp.TLV{nsdp.NewDeviceModel("GS108Ev3"), nsdp.NewDeviceName("switch1"), nsdp.NewDeviceIP(net.IP{10, 1, 0, 3})}, response.Body)
+	require.True(t, response.Completeness.Complete())
+}
+
+func TestConnSplitRequestBroadcast(t *testing.T) {
+	device1 := getStaticMAC()
+	device2 := net.HardwareAddr{0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestSplitResponse(device1, nsdp.NewDeviceModel("GS108Ev3")), newTestSplitResponse(device2, nsdp.NewDeviceModel("GS105Ev2")))
+	responder.AddResponses(newTestSplitResponse(device2, nsdp.NewDeviceName("switch2")), newTestSplitResponse(device1, nsdp.NewDeviceName("switch1")))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 80
+	conn.ReceiveTimeout = 500 * time.Millisecond
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.AppendTLV(nsdp.EmptyDeviceModel())
+	msg.AppendTLV(nsdp.EmptyDeviceName())
+	start := time.Now()
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	// Both parts are answered within a single receive timeout
+	require.Less(t, time.Since(start), 2*conn.ReceiveTimeout)
+	require.Equal(t, 2, len(responses))
+	require.Equal(t, []nsdp.TLV{nsdp.NewDeviceModel("GS108Ev3"), nsdp.NewDeviceName("switch1")}, responses[device1.String()].Body)
+	require.Equal(t, []nsdp.TLV{nsdp.NewDeviceModel("GS105Ev2"), nsdp.NewDeviceName("switch2")}, responses[device2.String()].Body)
+}
+
+func TestConnSplitRequestBroadcastDeviceLimit(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceModel("GS108Ev3")))
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceName("switch1")))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 80
+	conn.ReceiveDeviceLimit = 1
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.AppendTLV(nsdp.EmptyDeviceModel())
+	msg.AppendTLV(nsdp.EmptyDeviceName())
+	start := time.Now()
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	// The device limit is only reached after the device has answered both parts
+	require.Less(t, time.Since(start), conn.ReceiveTimeout)
+	require.Equal(t, []nsdp.TLV{nsdp.NewDeviceModel("GS108Ev3"), nsdp.NewDeviceName("switch1")}, responses[device.String()].Body)
+}
+
+func TestConnSplitRequestResultError(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceModel("GS108Ev3")))
+	failure := nsdp.NewMessage(nsdp.ReadResponse)
+	failure.Header.DeviceAddress = device
+	failure.Header.Result = nsdp.ResultInvalidValue
+	failure.Header.SetErrorTLV(nsdp.TypeDeviceLocation)
+	responder.AddResponses(hex.EncodeToString(failure.Marshal()))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 100
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.Header.DeviceAddress = device
+	msg.AppendTLV(nsdp.EmptyDeviceModel())
+	msg.AppendTLV(nsdp.EmptyDeviceLocation())
+	responses, err := conn.SendReceiveMessage(msg)
+	require.ErrorIs(t, err, nsdp.ErrInvalidValue)
+	require.Equal(t, []nsdp.TLV{nsdp.NewDeviceModel("GS108Ev3")}, responses[device.String()].Body)
+	require.Equal(t, nsdp.TypeDeviceLocation, responses[device.String()].Header.ErrorTLV())
+}
+
+func TestConnSplitRequestDisabled(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceModel("GS108Ev3"), nsdp.NewDeviceName("switch1")))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudget = 0
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.Header.DeviceAddress = device
+	msg.AppendTLV(nsdp.EmptyDeviceModel())
+	msg.AppendTLV(nsdp.EmptyDeviceName())
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(responses[device.String()].Body))
+}
+
+func TestConnSplitRequestModelBudget(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(newTestModelResponse(device))
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceModel("GS108Ev3"), nsdp.NewDeviceName("switch1")))
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceModel("GS108Ev3")))
+	responder.AddResponses(newTestSplitResponse(device, nsdp.NewDeviceName("switch1")))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.DatagramBudgets = map[string]uint{"GS108E": 80, "GS1": 1000}
+	_, err = conn.ProbeCapabilities(device)
+	require.NoError(t, err)
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.Header.DeviceAddress = device
+	msg.AppendTLV(nsdp.EmptyDeviceModel())
+	msg.AppendTLV(nsdp.EmptyDeviceName())
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, []nsdp.TLV{nsdp.NewDeviceModel("GS108Ev3"), nsdp.NewDeviceName("switch1")}, responses[device.String()].Body)
+}
+
+func newTestSplitResponse(device net.HardwareAddr, tlvs ...nsdp.TLV) string {
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.Header.DeviceAddress = device
+	for _, tlv := range tlvs {
+		response.AppendTLV(tlv)
+	}
+	return hex.EncodeToString(response.Marshal())
+}
@@ -8,7 +8,16 @@
 package nsdp_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tdrn-org/go-nsdp"
@@ -16,6 +25,11 @@ import (
 
 const connTestResponderTarget string = "localhost:0"
 
+// Captured read responses of two GS108Ev3 switches
+const connTestResponseSwitch1 string = "0102000000000000bcd07432b8dc6cb0ce1c8394000099d14e534450000000000001000847533130384576330003000773776974636831000400066cb0ce1c839400050000000600040a01000300070004ffff0000000800040a010001000b000100000d0007322e30362e3137000e0000000f0001010c0000030105000c0000030200000c0000030304000c0000030400000c0000030504000c0000030600000c0000030700000c0000030800001000003101000000011b86e2c2000000000d159e3800000000000000000000000000000000000000000000000000000000000000001000003102000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000100000310300000000039bd6ce000000000874984f000000000000000000000000000000000000000000000000000000000000000010000031040000000000133f340000000000cf6d03000000000000000000000000000000000000000000000000000000000000000010000031050000000009668768000000010afa8d1d0000000000000000000000000000000000000000000000000000000000000000100000310600000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000031070000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000003108000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ffff0000"
+const connTestResponseSwitch2 string = "0102000000000000bcd07432b8dce4f4c6ffa7a2000099d14e53445000000000000100084753313038457633000300077377697463683200040006e4f4c6ffa7a200050000000600040a01000400070004ffff0000000800040a010001000b000100000d0007322e30362e3137000e0000000f0001010c0000030105000c0000030205000c0000030302000c0000030404000c0000030500000c0000030600000c0000030700000c0000030800001000003101000000009d57dcbf000000000e10739f0000000000000000000000000000000000000000000000000000000000000000100000310200000000091cf6760000000028dfe4ca000000000000000000000000000000000000000000000000000000000000000010000031030000000005a930200000000081ccfd9a000000000000000000000000000000000000000000000000000000000000000010000031040000000000c2ebb8000000000cd0177800000000000000000000000000000000000000000000000000000000000000001000003105000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000100000310600000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000031070000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000003108000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ffff0000"
+const connTestResponseSwitch2Unicast string = "0102000000000000bcd07432b8dce4f4c6ffa7a200001a414e53445000000000000100084753313038457633000300077377697463683200040006e4f4c6ffa7a200050000000600040a01000400070004ffff0000000800040a010001000b000100000d0007322e30362e3137000e0000000f0001010c0000030105000c0000030205000c0000030302000c0000030404000c0000030500000c0000030600000c0000030700000c0000030800001000003101000000009d55f306000000000e100c210000000000000000000000000000000000000000000000000000000000000000100000310200000000091c99ed0000000028ddfe8b000000000000000000000000000000000000000000000000000000000000000010000031030000000005a92fe00000000081cb4ea2000000000000000000000000000000000000000000000000000000000000000010000031040000000000c2e89b000000000cce6b8c00000000000000000000000000000000000000000000000000000000000000001000003105000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000100000310600000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000031070000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000003108000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ffff0000"
+
 func TestConn(t *testing.T) {
 	conn, err := nsdp.NewConn(nsdp.IPv4BroadcastTarget, true)
 	require.NoError(t, err)
@@ -30,9 +44,7 @@ func TestConnSendReceiveMessageBroadcast(t *testing.T) {
 	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
 	require.NoError(t, err)
 	defer responder.Stop()
-	responder.AddResponses(
-		"0102000000000000bcd07432b8dc6cb0ce1c8394000099d14e534450000000000001000847533130384576330003000773776974636831000400066cb0ce1c839400050000000600040a01000300070004ffff0000000800040a010001000b000100000d0007322e30362e3137000e0000000f0001010c0000030105000c0000030200000c0000030304000c0000030400000c0000030504000c0000030600000c0000030700000c0000030800001000003101000000011b86e2c2000000000d159e3800000000000000000000000000000000000000000000000000000000000000001000003102000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000100000310300000000039bd6ce000000000874984f000000000000000000000000000000000000000000000000000000000000000010000031040000000000133f340000000000cf6d03000000000000000000000000000000000000000000000000000000000000000010000031050000000009668768000000010afa8d1d0000000000000000000000000000000000000000000000000000000000000000100000310600000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000031070000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000003108000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ffff0000",
-		"0102000000000000bcd07432b8dce4f4c6ffa7a2000099d14e53445000000000000100084753313038457633000300077377697463683200040006e4f4c6ffa7a200050000000600040a01000400070004ffff0000000800040a010001000b000100000d0007322e30362e3137000e0000000f0001010c0000030105000c0000030205000c0000030302000c0000030404000c0000030500000c0000030600000c0000030700000c0000030800001000003101000000009d57dcbf000000000e10739f0000000000000000000000000000000000000000000000000000000000000000100000310200000000091cf6760000000028dfe4ca000000000000000000000000000000000000000000000000000000000000000010000031030000000005a930200000000081ccfd9a000000000000000000000000000000000000000000000000000000000000000010000031040000000000c2ebb8000000000cd0177800000000000000000000000000000000000000000000000000000000000000001000003105000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000100000310600000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000031070000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000003108000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ffff0000")
+	responder.AddResponses(connTestResponseSwitch1, connTestResponseSwitch2)
 	err = responder.Start()
 	require.NoError(t, err)
 	conn, err := nsdp.NewConn(responder.Target(), true)
@@ -49,17 +61,57 @@ func TestConnSendReceiveMessageUnicast(t *testing.T) {
 	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
 	require.NoError(t, err)
 	defer responder.Stop()
-	responder.AddResponses("0102000000000000bcd07432b8dce4f4c6ffa7a200001a414e53445000000000000100084753313038457633000300077377697463683200040006e4f4c6ffa7a200050000000600040a01000400070004ffff0000000800040a010001000b000100000d0007322e30362e3137000e0000000f0001010c0000030105000c0000030205000c0000030302000c0000030404000c0000030500000c0000030600000c0000030700000c0000030800001000003101000000009d55f306000000000e100c210000000000000000000000000000000000000000000000000000000000000000100000310200000000091c99ed0000000028ddfe8b000000000000000000000000000000000000000000000000000000000000000010000031030000000005a92fe00000000081cb4ea2000000000000000000000000000000000000000000000000000000000000000010000031040000000000c2e89b000000000cce6b8c00000000000000000000000000000000000000000000000000000000000000001000003105000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000100000310600000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000031070000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000003108000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000ffff0000")
+	responder.AddResponses(connTestResponseSwitch2Unicast)
 	err = responder.Start()
 	require.NoError(t, err)
 	conn, err := nsdp.NewConn(responder.Target(), true)
 	require.NoError(t, err)
 	defer conn.Close()
+	conn.CheckCompleteness = true
 	msg := prepareTestMessage()
 	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
 	responses, err := conn.SendReceiveMessage(msg)
 	require.NoError(t, err)
 	require.Equal(t, 1, len(responses))
+	for _, response := range responses {
+		// The captured response lacks a PortCount TLV, hence its ports cannot be checked
+		require.False(t, response.Completeness.Complete())
+		require.Empty(t, response.Completeness.MissingTypes)
+		require.Empty(t, response.Completeness.MissingPorts)
+		require.Equal(t, []nsdp.Type{nsdp.TypePortStatus, nsdp.TypePortStatistic}, response.Completeness.UncheckedPorts)
+	}
+}
+
+func TestConnSendReceiveMessageResultError(t *testing.T) {
+	device := getStaticMAC()
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	response := nsdp.NewMessage(nsdp.WriteResponse)
+	response.Header.DeviceAddress = device
+	response.Header.Result = nsdp.ResultInvalidPassword
+	response.Header.SetErrorTLV(nsdp.TypePassword)
+	responder.AddResponses(hex.EncodeToString(response.Marshal()))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	msg := nsdp.NewMessage(nsdp.WriteRequest)
+	msg.Header.DeviceAddress = device
+	msg.AppendTLV(nsdp.NewRawTLV(nsdp.TypePassword, []byte("wrong")))
+	msg.AppendTLV(nsdp.NewDeviceName("switch"))
+	responses, err := conn.SendReceiveMessage(msg)
+	require.Equal(t, 1, len(responses))
+	require.ErrorIs(t, err, nsdp.ErrOperationFailed)
+	require.ErrorIs(t, err, nsdp.ErrInvalidPassword)
+	require.NotErrorIs(t, err, nsdp.ErrInvalidValue)
+	var deviceErrs nsdp.DeviceErrors
+	require.ErrorAs(t, err, &deviceErrs)
+	var resultErr *nsdp.ResultError
+	require.ErrorAs(t, deviceErrs[device.String()], &resultErr)
+	require.Equal(t, nsdp.TypePassword, resultErr.TLV)
+	require.Equal(t, device, resultErr.Device)
 }
 
 func prepareTestMessage() *nsdp.Message {
@@ -79,3 +131,372 @@ func prepareTestMessage() *nsdp.Message {
 	message.AppendTLV(nsdp.EmptyNextFWSlot())
 	return message
 }
+
+func TestConnSendReceiveMessageMultiDatagram(t *testing.T) {
+	device1 := getStaticMAC()
+	device2 := net.HardwareAddr{0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+	response1a := nsdp.NewMessage(nsdp.ReadResponse)
+	response1a.Header.DeviceAddress = device1
+	response1a.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link1GFull))
+	response1b := nsdp.NewMessage(nsdp.ReadResponse)
+	response1b.Header.DeviceAddress = device1
+	response1b.AppendTLV(nsdp.NewPortStatus(2, nsdp.LinkDown))
+	response2 := nsdp.NewMessage(nsdp.ReadResponse)
+	response2.Header.DeviceAddress = device2
+	response2.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link100MFull))
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(hex.EncodeToString(response1a.Marshal()), hex.EncodeToString(response1b.Marshal()), hex.EncodeToString(response1a.Marshal()), hex.EncodeToString(response2.Marshal()))
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveTimeout = 200 * time.Millisecond
+	msg := nsdp.NewMessage(nsdp.ReadRequest)
+	msg.AppendTLV(nsdp.EmptyPortStatus())
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(responses))
+	require.Equal(t, []nsdp.TLV{nsdp.NewPortStatus(1, nsdp.Link1GFull), nsdp.NewPortStatus(2, nsdp.LinkDown)}, responses[device1.String()].Body)
+	require.Equal(t, uint(1), responses[device1.String()].Duplicates)
+	require.Equal(t, []nsdp.TLV{nsdp.NewPortStatus(1, nsdp.Link100MFull)}, responses[device2.String()].Body)
+	require.Equal(t, uint(0), responses[device2.String()].Duplicates)
+}
+
+func TestConnSendReceiveMessageContextCancel(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveTimeout = 10 * time.Second
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = conn.SendReceiveMessageContext(ctx, prepareTestMessage())
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), conn.ReceiveTimeout)
+	require.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestConnSendReceiveMessageContextDeadline(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = getStaticMAC()
+	start := time.Now()
+	_, err = conn.SendReceiveMessageContext(ctx, msg)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), conn.ReceiveTimeout)
+	_, err = conn.SendReceiveMessageContext(ctx, msg)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConnCloseStopsReceiver(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool {
+		return strings.Contains(connGoroutineStacks(), "(*Conn).receiveLoop")
+	}, time.Second, 10*time.Millisecond)
+	err = conn.Close()
+	require.NoError(t, err)
+	require.Empty(t, connGoroutineStacks())
+	_, err = conn.SendReceiveMessage(prepareTestMessage())
+	require.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestConnClosePendingRequest(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	conn.ReceiveTimeout = 10 * time.Second
+	result := make(chan error)
+	go func() {
+		_, err := conn.SendReceiveMessage(prepareTestMessage())
+		result <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	err = conn.Close()
+	require.NoError(t, err)
+	select {
+	case err = <-result:
+		require.ErrorIs(t, err, net.ErrClosed)
+	case <-time.After(conn.ReceiveTimeout):
+		require.Fail(t, "pending request not finished by Close")
+	}
+	require.Empty(t, connGoroutineStacks())
+}
+
+func TestConnReceiveDeviceLimitNoCrossTalk(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch1, connTestResponseSwitch2)
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveDeviceLimit = 1
+	responses, err := conn.SendReceiveMessage(prepareTestMessage())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	// The 2nd response of the 1st request must not be taken for the response of the 2nd request
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	responses, err = conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	for _, response := range responses {
+		require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch2Unicast).Body, response.Body)
+	}
+}
+
+func TestConnReceiveQueueOverflow(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch1, connTestResponseSwitch2, connTestResponseSwitch1, connTestResponseSwitch2)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	conn.ReceiveQueueLength = 1
+	conn.ReceiveTimeout = 200 * time.Millisecond
+	_, err = conn.SendReceiveMessage(prepareTestMessage())
+	require.NoError(t, err)
+	err = conn.Close()
+	require.NoError(t, err)
+	require.Empty(t, connGoroutineStacks())
+}
+
+func TestConnReceiveQueueNoDrops(t *testing.T) {
+	const deviceCount = 40
+	responses := make([]string, 0, deviceCount)
+	for i := range deviceCount {
+		response := nsdp.NewMessage(nsdp.ReadResponse)
+		response.Header.DeviceAddress = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, byte(i)}
+		response.AppendTLV(nsdp.NewDeviceName("switch"))
+		responses = append(responses, hex.EncodeToString(response.Marshal()))
+	}
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(responses...)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveQueueLength = 1
+	conn.ReceiveTimeout = 1 * time.Second
+	conn.ReceiveDeviceLimit = deviceCount
+	devices := make(map[string]bool)
+	for response, err := range conn.SendReceiveMessageStream(context.Background(), prepareTestMessage()) {
+		require.NoError(t, err)
+		devices[response.Header.DeviceAddress.String()] = true
+		// Consume slower than the responses arrive
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Equal(t, deviceCount, len(devices))
+}
+
+var errTestRead = errors.New("test read error")
+
+func TestConnReadErrorTransient(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	restore := nsdp.InjectReadErrors(3, 30*time.Millisecond, errTestRead)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	restore()
+	require.NoError(t, err)
+	defer conn.Close()
+	// The request is pending while the read errors occur, but is not concerned by them
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+}
+
+func TestConnReadErrorPersistent(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	restore := nsdp.InjectReadErrors(-1, 0, errTestRead)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	restore()
+	require.NoError(t, err)
+	conn.ReceiveTimeout = 10 * time.Second
+	start := time.Now()
+	_, err = conn.SendReceiveMessage(prepareTestMessage())
+	require.ErrorIs(t, err, errTestRead)
+	require.Less(t, time.Since(start), conn.ReceiveTimeout)
+	_, err = conn.SendReceiveMessage(prepareTestMessage())
+	require.ErrorIs(t, err, errTestRead)
+	err = conn.Close()
+	require.NoError(t, err)
+	require.Empty(t, connGoroutineStacks())
+}
+
+func TestConnModifySettings(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), false)
+	require.NoError(t, err)
+	defer conn.Close()
+	// Modifying the settings in between requests must not race with the receive goroutine (see go test -race)
+	conn.ReceiveBufferSize = 4096
+	conn.StrictDecoding = true
+	conn.Debug = true
+	buffer := &bytes.Buffer{}
+	conn.Capture, err = nsdp.NewCaptureWriter(buffer)
+	require.NoError(t, err)
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+}
+
+// connGoroutineStacks gets the stacks of all goroutines currently executing a Conn method.
+func connGoroutineStacks() string {
+	buffer := make([]byte, 1<<20)
+	buffer = buffer[:runtime.Stack(buffer, true)]
+	stacks := make([]string, 0)
+	for _, stack := range strings.Split(string(buffer), "\n\n") {
+		if strings.Contains(stack, "go-nsdp.(*Conn)") {
+			stacks = append(stacks, stack)
+		}
+	}
+	return strings.Join(stacks, "\n\n")
+}
+
+func TestConnSendReceiveMessageStream(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch1, connTestResponseSwitch1, connTestResponseSwitch2)
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveTimeout = 10 * time.Second
+	start := time.Now()
+	received := make([]*nsdp.Message, 0)
+	for response, err := range conn.SendReceiveMessageStream(context.Background(), prepareTestMessage()) {
+		require.NoError(t, err)
+		received = append(received, response)
+		if len(received) == 2 {
+			break
+		}
+	}
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch1).Body, received[0].Body)
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch2).Body, received[1].Body)
+	// Stopping the iteration early must not affect the next request
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	require.Contains(t, responses, "e4:f4:c6:ff:a7:a2")
+}
+
+func TestConnSendReceiveMessageStreamError(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.ReceiveTimeout = 100 * time.Millisecond
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = getStaticMAC()
+	yielded := 0
+	for response, err := range conn.SendReceiveMessageStream(context.Background(), msg) {
+		require.Nil(t, response)
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		yielded++
+	}
+	require.Equal(t, 1, yielded)
+	invalid := nsdp.NewMessage(nsdp.WriteRequest)
+	invalid.AppendTLV(nsdp.NewLEDControl(nsdp.LEDMode(0x7f)))
+	yielded = 0
+	for response, err := range conn.SendReceiveMessageStream(context.Background(), invalid) {
+		require.Nil(t, response)
+		require.Error(t, err)
+		yielded++
+	}
+	require.Equal(t, 1, yielded)
+}
+
+func TestConnSendReceiveMessageStreamStopNoCrossTalk(t *testing.T) {
+	responder, err := nsdp.NewTestResponder(connTestResponderTarget)
+	require.NoError(t, err)
+	defer responder.Stop()
+	responder.AddResponses(connTestResponseSwitch1, connTestResponseSwitch2)
+	responder.AddResponses(connTestResponseSwitch2Unicast)
+	err = responder.Start()
+	require.NoError(t, err)
+	conn, err := nsdp.NewConn(responder.Target(), true)
+	require.NoError(t, err)
+	defer conn.Close()
+	yielded := 0
+	for response, err := range conn.SendReceiveMessageStream(context.Background(), prepareTestMessage()) {
+		require.NoError(t, err)
+		require.NotNil(t, response)
+		yielded++
+		break
+	}
+	require.Equal(t, 1, yielded)
+	// The 2nd response of the stopped broadcast must not be taken for the response of the next request
+	msg := prepareTestMessage()
+	msg.Header.DeviceAddress = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	responses, err := conn.SendReceiveMessage(msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(responses))
+	for _, response := range responses {
+		require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch2Unicast).Body, response.Body)
+	}
+}
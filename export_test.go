@@ -0,0 +1,33 @@
+// export_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// InjectReadErrors makes the connections created afterwards fail their first count datagram reads (all reads if
+// count is negative) with the given error, each failing read taking the given delay. The returned function restores
+// the regular reading.
+func InjectReadErrors(count int, delay time.Duration, err error) func() {
+	readHook = func(read datagramReadFunc) datagramReadFunc {
+		failed := &atomic.Int64{}
+		return func(b []byte) (int, *net.UDPAddr, error) {
+			if count < 0 || failed.Add(1) <= int64(count) {
+				time.Sleep(delay)
+				return 0, nil, err
+			}
+			return read(b)
+		}
+	}
+	return func() {
+		readHook = nil
+	}
+}
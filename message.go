@@ -11,7 +11,9 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
+	"slices"
 	"strings"
 )
 
@@ -21,9 +23,12 @@ import (
 // The Header defines the general message processing rules (espcially type of operation and target device). The TLV elements define
 // the actual message content.
 type Message struct {
-	Header *Header // Message header
-	Body   []TLV   // Message body (payload)
-	EOM    *EOM    // End-of-message marker
+	Header       *Header       // Message header
+	Body         []TLV         // Message body (payload)
+	EOM          *EOM          // End-of-message marker
+	Completeness *Completeness // Completeness report (only set for responses received via Conn with CheckCompleteness enabled)
+	Duplicates   uint          // Number of discarded duplicate datagrams (only set for responses received via Conn)
+	Attempts     uint          // Number of request transmissions needed (only set for unicast responses received via Conn)
 }
 
 // NewMessage constructs a new message for the given operation code with an empty list of TLVs.
@@ -48,102 +53,251 @@ func (m *Message) AppendTLV(tlv TLV) {
 	m.Body = append(m.Body, tlv)
 }
 
+// Validate checks whether all of the message's TLVs are valid for the message's operation.
+func (m *Message) Validate() error {
+	for _, tlv := range m.Body {
+		validator, ok := tlv.(tlvValidator)
+		if !ok {
+			continue
+		}
+		err := validator.validate(m.Header.Operation)
+		if err != nil {
+			return fmt.Errorf("invalid TLV type %04xh; cause: %w", tlv.Type(), err)
+		}
+	}
+	return nil
+}
+
 func (m *Message) String() string {
 	builder := &strings.Builder{}
+	m.writeString(builder, func(tlv TLV) TLV { return tlv })
+	return builder.String()
+}
+
+func (m *Message) writeString(builder *strings.Builder, textTLV func(TLV) TLV) {
 	m.Header.writeString(builder)
 	builder.WriteRune('\n')
 	for i, tlv := range m.Body {
-		builder.WriteString(fmt.Sprintf("TLV[%d]: %s\n", i, tlv))
+		builder.WriteString(fmt.Sprintf("TLV[%d]: %s\n", i, textTLV(tlv)))
 	}
 	m.EOM.writeString(builder)
-	return builder.String()
 }
 
 // Marshal encodes the message to its NSDP compliant byte stream.
 func (m *Message) Marshal() []byte {
-	buffer := &bytes.Buffer{}
-	m.MarshalBuffer(buffer)
-	return buffer.Bytes()
+	return m.appendBinary(nil)
 }
 
 // MarshalBuffer encodes the message to its NSDP compliant byte stream.
 func (m *Message) MarshalBuffer(buffer *bytes.Buffer) {
-	m.Header.marshalBuffer(buffer)
+	buffer.Write(m.appendBinary(buffer.AvailableBuffer()))
+}
+
+// AppendBinary encodes the message to its NSDP compliant byte stream and appends it to the given buffer.
+//
+// In contrast to Marshal, no intermediate buffers are allocated for the built-in TLV types. Re-using the
+// returned buffer for encoding further messages therefore avoids any allocation once the buffer has grown
+// to the needed size. In contrast to Marshal, TLV values exceeding the maximum TLV length are reported as an error.
+func (m *Message) AppendBinary(buf []byte) ([]byte, error) {
+	buf, err := m.encodeBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (m *Message) appendBinary(buf []byte) []byte {
+	buf, _ = m.encodeBinary(buf)
+	return buf
+}
+
+// encodeBinary encodes the message and appends it to the given buffer. TLV values exceeding the maximum TLV length
+// are reported as an error (the returned buffer nevertheless contains the complete message with truncated length).
+func (m *Message) encodeBinary(buf []byte) ([]byte, error) {
+	var err error
+	buf = m.Header.appendBinary(buf)
 	for _, tlv := range m.Body {
-		binary.Write(buffer, binary.BigEndian, tlv.Type())
-		if m.Header.Operation == ReadRequest {
-			binary.Write(buffer, binary.BigEndian, uint16(0))
-		} else {
-			binary.Write(buffer, binary.BigEndian, uint16(tlv.Length()))
-			buffer.Write(tlv.Value())
+		buf = binary.BigEndian.AppendUint16(buf, uint16(tlv.Type()))
+		lengthOffset := len(buf)
+		buf = binary.BigEndian.AppendUint16(buf, 0)
+		if m.Header.Operation != ReadRequest {
+			buf = appendTLVValue(buf, tlv)
+			valueLength := len(buf) - lengthOffset - 2
+			if valueLength > maxTLVLength && err == nil {
+				err = fmt.Errorf("excessive TLV length: %d (type: %04xh)", valueLength, tlv.Type())
+			}
+			binary.BigEndian.PutUint16(buf[lengthOffset:], uint16(valueLength))
 		}
 	}
-	m.EOM.marshalBuffer(buffer)
+	return m.EOM.appendBinary(buf), err
 }
 
 // UnmarshalMessage decodes a message from the given NSDP byte stream.
+//
+// TLVs of unrecognized type are decoded into RawTLV elements.
+//
+// As NSDP messages are received from unauthenticated (broadcast) traffic, the byte stream is treated as untrusted
+// input. Malformed byte streams are reported as an error, and the memory allocated during decoding is bounded
+// by a constant multiple of the byte stream's length.
 func UnmarshalMessage(buf []byte) (*Message, error) {
-	buffer := bytes.NewBuffer(buf)
-	return UnmarshalMessageBuffer(buffer)
+	msg := &Message{}
+	_, err := decodeMessage(buf, msg, false)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
 }
 
-// UnmarshalMessage decodes a message from the given NSDP byte stream.
+// UnmarshalMessageStrict decodes a message from the given NSDP byte stream.
+//
+// In contrast to UnmarshalMessage, TLVs of unrecognized type are considered an error.
+func UnmarshalMessageStrict(buf []byte) (*Message, error) {
+	msg := &Message{}
+	_, err := decodeMessage(buf, msg, true)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// UnmarshalMessageBuffer decodes a message from the given NSDP byte stream.
+//
+// TLVs of unrecognized type are decoded into RawTLV elements.
 func UnmarshalMessageBuffer(buffer *bytes.Buffer) (*Message, error) {
-	header, err := unmarshalHeaderBuffer(buffer)
+	return unmarshalMessageBuffer(buffer, false)
+}
+
+// UnmarshalMessageBufferStrict decodes a message from the given NSDP byte stream.
+//
+// In contrast to UnmarshalMessageBuffer, TLVs of unrecognized type are considered an error.
+func UnmarshalMessageBufferStrict(buffer *bytes.Buffer) (*Message, error) {
+	return unmarshalMessageBuffer(buffer, true)
+}
+
+func unmarshalMessageBuffer(buffer *bytes.Buffer, strict bool) (*Message, error) {
+	msg := &Message{}
+	decoded, err := decodeMessage(buffer.Bytes(), msg, strict)
+	buffer.Next(decoded)
 	if err != nil {
 		return nil, err
 	}
-	tlvs := make([]TLV, 0)
+	return msg, nil
+}
+
+// Upper bound for the length of a NSDP byte stream (NSDP messages are transferred via UDP)
+const maxMessageLength int = 0xffff
+
+// Maximum length of a single TLV value (as encoded in the TLV's 16 bit length field)
+const maxTLVLength int = 0xffff
+
+// decodeMessage decodes the given byte stream into the given message (re-using its storage) and returns
+// the number of bytes consumed.
+func decodeMessage(buf []byte, msg *Message, strict bool) (int, error) {
+	// Decoding allocates at most a constant multiple of the byte stream length, as each TLV
+	// value is bounded by the remaining data and each TLV consumes at least 4 bytes.
+	if len(buf) > maxMessageLength {
+		return 0, fmt.Errorf("excessive message length: %d", len(buf))
+	}
+	if msg.Header == nil {
+		msg.Header = &Header{}
+	}
+	err := msg.Header.unmarshalBinary(buf)
+	if err != nil {
+		return 0, err
+	}
+	offset := headerLength
+	previous := msg.Body
+	body := msg.Body[:0]
+	if body == nil {
+		body = make([]TLV, 0)
+	}
 	for {
-		tlvType, tlvLength, err := unmarshalMessageTLVTypeLength(buffer)
+		tlvType, tlvLength, err := decodeMessageTLVTypeLength(buf[offset:])
 		if err != nil {
-			return nil, err
+			return offset, err
 		}
+		offset += 4
 		if tlvType == uint16(TypeEOM) {
 			if tlvLength != 0 {
-				return nil, fmt.Errorf("unexpected EOM marker: %04x%04xh", tlvType, tlvLength)
+				return offset, fmt.Errorf("unexpected EOM marker: %04x%04xh", tlvType, tlvLength)
 			}
 			break
 		}
-		tlv, err := unmarshalMessageTLVValue(buffer, tlvType, tlvLength)
+		tlvValue := buf[offset : offset+int(tlvLength)]
+		offset += int(tlvLength)
+		if msg.Header.Operation == ReadRequest {
+			// Read requests carry no TLV values (see MarshalBuffer); skip any value bytes
+			tlvValue = nil
+		}
+		var reuse TLV
+		if len(body) < len(previous) {
+			reuse = previous[len(body)]
+		}
+		tlv, err := decodeMessageTLV(reuse, tlvType, tlvValue, strict)
 		if err != nil {
-			return nil, err
+			return offset, err
 		}
-		tlvs = append(tlvs, tlv)
+		body = append(body, tlv)
 	}
-	return &Message{
-		Header: header,
-		Body:   tlvs,
-		EOM:    newEOM(),
-	}, nil
+	if len(body) < len(previous) {
+		// Release TLVs no longer part of the message
+		clear(previous[len(body):])
+	}
+	msg.Body = body
+	if msg.EOM == nil {
+		msg.EOM = newEOM()
+	} else {
+		msg.EOM.Marker = uint32(EOMMarker)
+	}
+	msg.Completeness = nil
+	return offset, nil
 }
 
-func unmarshalMessageTLVTypeLength(buffer *bytes.Buffer) (uint16, uint16, error) {
-	var tlvType uint16
-	err := binary.Read(buffer, binary.BigEndian, &tlvType)
-	if err != nil {
-		return 0, 0, fmt.Errorf("error while decoding TLV type; cause: %v", err)
+func decodeMessageTLVTypeLength(buf []byte) (uint16, uint16, error) {
+	if len(buf) < 2 {
+		return 0, 0, fmt.Errorf("error while decoding TLV type; cause: %v", io.ErrUnexpectedEOF)
 	}
-	var tlvLength uint16
-	err = binary.Read(buffer, binary.BigEndian, &tlvLength)
-	if err != nil {
-		return 0, 0, fmt.Errorf("error while decoding TLV length; cause: %v", err)
+	tlvType := binary.BigEndian.Uint16(buf[0:2])
+	if len(buf) < 4 {
+		return 0, 0, fmt.Errorf("error while decoding TLV length; cause: %v", io.ErrUnexpectedEOF)
 	}
-	if tlvLength > uint16(buffer.Len()) {
-		return 0, 0, fmt.Errorf("excessive TLV length: %d (remaining: %d)", tlvLength, buffer.Len())
+	tlvLength := binary.BigEndian.Uint16(buf[2:4])
+	if int(tlvLength) > len(buf)-4 {
+		return 0, 0, fmt.Errorf("excessive TLV length: %d (remaining: %d)", tlvLength, len(buf)-4)
 	}
 	return tlvType, tlvLength, nil
 }
 
-func unmarshalMessageTLVValue(buffer *bytes.Buffer, tlvType uint16, tlvLength uint16) (TLV, error) {
-	tlvValue := make([]byte, tlvLength)
-	_, err := buffer.Read(tlvValue)
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding TLV value; cause: %v", err)
+// decodeMessageTLV decodes a single TLV value. If the built-in decoder is registered for the TLV type, the given TLV
+// is updated in place if possible. Otherwise the registered decoder creates a new TLV. In both cases the value is copied, as it refers to the caller's byte stream.
+func decodeMessageTLV(reuse TLV, tlvType uint16, tlvValue []byte, strict bool) (TLV, error) {
+	registration, found := lookupTLVRegistration(Type(tlvType))
+	if !found && strict {
+		return nil, fmt.Errorf("error while decoding TLV type %04xh; cause: %w: %04xh", tlvType, errUnrecognizedTLVType, tlvType)
+	}
+	var tlv TLV
+	var err error
+	if found && !registration.builtin {
+		// Custom decoders are always invoked (they may validate or transform the value)
+		tlv, err = registration.decoder(slices.Clone(tlvValue))
+	} else {
+		_, isRaw := reuse.(*RawTLV)
+		if reuse == nil || uint16(reuse.Type()) != tlvType || isRaw == found {
+			if found {
+				reuse, _ = registration.decoder(nil)
+			} else {
+				reuse = NewRawTLV(Type(tlvType), nil)
+			}
+		}
+		unmarshaler, ok := reuse.(tlvValueUnmarshaler)
+		if ok {
+			tlv, err = reuse, unmarshaler.unmarshalValue(tlvValue)
+		} else {
+			tlv, err = registration.decoder(slices.Clone(tlvValue))
+		}
 	}
-	tlv, err := unmarshalTLV(tlvType, tlvValue)
 	if err != nil {
-		return nil, fmt.Errorf("error while decoding TLV type %04xh; cause: %v", tlvType, err)
+		return nil, fmt.Errorf("error while decoding TLV type %04xh; cause: %w", tlvType, err)
 	}
 	return tlv, nil
 }
@@ -0,0 +1,129 @@
+// message_access.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"net"
+)
+
+// First gets the first TLV of type T contained in the given message.
+//
+// E.g. First[*DeviceName](msg) gets the DeviceName TLV of a read response.
+func First[T TLV](msg *Message) (T, bool) {
+	for _, tlv := range msg.Body {
+		typedTLV, ok := tlv.(T)
+		if ok {
+			return typedTLV, true
+		}
+	}
+	var none T
+	return none, false
+}
+
+// All gets all TLVs of type T contained in the given message (in message order).
+func All[T TLV](msg *Message) []T {
+	typedTLVs := make([]T, 0)
+	for _, tlv := range msg.Body {
+		typedTLV, ok := tlv.(T)
+		if ok {
+			typedTLVs = append(typedTLVs, typedTLV)
+		}
+	}
+	return typedTLVs
+}
+
+// Ports gets all per-port TLVs of type T contained in the given message keyed by their port number.
+//
+// E.g. Ports[*PortStatistic](msg) gets the port statistics of a read response.
+func Ports[T PortTLV](msg *Message) map[uint8]T {
+	portTLVs := make(map[uint8]T)
+	for _, tlv := range msg.Body {
+		typedTLV, ok := tlv.(T)
+		if ok {
+			portTLVs[typedTLV.PortNumber()] = typedTLV
+		}
+	}
+	return portTLVs
+}
+
+// Port gets the per-port TLV of type T for the given port number.
+func Port[T PortTLV](msg *Message, port uint8) (T, bool) {
+	for _, tlv := range msg.Body {
+		typedTLV, ok := tlv.(T)
+		if ok && typedTLV.PortNumber() == port {
+			return typedTLV, true
+		}
+	}
+	var none T
+	return none, false
+}
+
+// DeviceInfo provides a flat view on the device information contained in a read response.
+//
+// Information not contained in the response is left at its zero value.
+type DeviceInfo struct {
+	Address        net.HardwareAddr         // Hardware address of the responding device (taken from the message header)
+	Model          string                   // See DeviceModel
+	Name           string                   // See DeviceName
+	MAC            net.HardwareAddr         // See DeviceMAC
+	Location       string                   // See DeviceLocation
+	IP             net.IP                   // See DeviceIP
+	Netmask        net.IP                   // See DeviceNetmask
+	Router         net.IP                   // See RouterIP
+	DHCPMode       DHCPModeValue            // See DHCPMode
+	FWVersionSlot1 string                   // See FWVersionSlot1
+	FWVersionSlot2 string                   // See FWVersionSlot2
+	NextFWSlot     uint8                    // See NextFWSlot
+	PortCount      uint8                    // See PortCount
+	PowerSaving    PowerSavingMode          // See PowerSaving
+	LEDMode        LEDMode                  // See LEDControl
+	PortStatus     map[uint8]*PortStatus    // See PortStatus
+	PortStatistic  map[uint8]*PortStatistic // See PortStatistic
+}
+
+// DeviceInfo gets the device information contained in the message.
+func (m *Message) DeviceInfo() *DeviceInfo {
+	info := &DeviceInfo{
+		Address:       m.Header.DeviceAddress,
+		PortStatus:    Ports[*PortStatus](m),
+		PortStatistic: Ports[*PortStatistic](m),
+	}
+	for _, tlv := range m.Body {
+		switch typedTLV := tlv.(type) {
+		case *DeviceModel:
+			info.Model = typedTLV.Model
+		case *DeviceName:
+			info.Name = typedTLV.Name
+		case *DeviceMAC:
+			info.MAC = typedTLV.MAC
+		case *DeviceLocation:
+			info.Location = typedTLV.Location
+		case *DeviceIP:
+			info.IP = typedTLV.IP
+		case *DeviceNetmask:
+			info.Netmask = typedTLV.Netmask
+		case *RouterIP:
+			info.Router = typedTLV.IP
+		case *DHCPMode:
+			info.DHCPMode = typedTLV.Mode
+		case *FWVersionSlot1:
+			info.FWVersionSlot1 = typedTLV.Version
+		case *FWVersionSlot2:
+			info.FWVersionSlot2 = typedTLV.Version
+		case *NextFWSlot:
+			info.NextFWSlot = typedTLV.Slot
+		case *PortCount:
+			info.PortCount = typedTLV.Count
+		case *PowerSaving:
+			info.PowerSaving = typedTLV.Mode
+		case *LEDControl:
+			info.LEDMode = typedTLV.Mode
+		}
+	}
+	return info
+}
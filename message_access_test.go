@@ -0,0 +1,72 @@
+// message_access_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestFirst(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	deviceName, found := nsdp.First[*nsdp.DeviceName](msg)
+	require.True(t, found)
+	require.Equal(t, "switch1", deviceName.Name)
+	_, found = nsdp.First[*nsdp.LEDControl](msg)
+	require.False(t, found)
+}
+
+func TestAll(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	portStatus := nsdp.All[*nsdp.PortStatus](msg)
+	require.Equal(t, 8, len(portStatus))
+	require.Equal(t, uint8(1), portStatus[0].Port)
+	require.Equal(t, 0, len(nsdp.All[*nsdp.PowerSaving](msg)))
+}
+
+func TestPorts(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	portStatistic := nsdp.Ports[*nsdp.PortStatistic](msg)
+	require.Equal(t, 8, len(portStatistic))
+	require.Equal(t, uint64(0x011b86e2c2), portStatistic[1].Received)
+	portStatus, found := nsdp.Port[*nsdp.PortStatus](msg, 3)
+	require.True(t, found)
+	require.Equal(t, nsdp.Link100MFull, portStatus.Status)
+	_, found = nsdp.Port[*nsdp.PortStatus](msg, 9)
+	require.False(t, found)
+}
+
+func TestDeviceInfo(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	info := msg.DeviceInfo()
+	require.Equal(t, "GS108Ev3", info.Model)
+	require.Equal(t, "switch1", info.Name)
+	require.Equal(t, net.HardwareAddr{0x6c, 0xb0, 0xce, 0x1c, 0x83, 0x94}, info.MAC)
+	require.Equal(t, info.MAC, info.Address)
+	require.Equal(t, "", info.Location)
+	require.Equal(t, net.IP{10, 1, 0, 3}, info.IP)
+	require.Equal(t, net.IP{255, 255, 0, 0}, info.Netmask)
+	require.Equal(t, net.IP{10, 1, 0, 1}, info.Router)
+	require.Equal(t, nsdp.DHCPDisabled, info.DHCPMode)
+	require.Equal(t, "2.06.17", info.FWVersionSlot1)
+	require.Equal(t, uint8(1), info.NextFWSlot)
+	require.Equal(t, 8, len(info.PortStatus))
+	require.Equal(t, 8, len(info.PortStatistic))
+}
+
+func unmarshalTestMessage(t testing.TB, encoded string) *nsdp.Message {
+	buf, err := hex.DecodeString(encoded)
+	require.NoError(t, err)
+	msg, err := nsdp.UnmarshalMessage(buf)
+	require.NoError(t, err)
+	return msg
+}
@@ -0,0 +1,99 @@
+// message_binary.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MarshalBinary encodes the message to its NSDP compliant byte stream (see Marshal). In contrast to Marshal, TLV values
+// exceeding the maximum TLV length are reported as an error.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// UnmarshalBinary decodes the message from the given NSDP byte stream.
+//
+// TLVs of unrecognized type are decoded into RawTLV elements (see UnmarshalMessage). In contrast to UnmarshalMessage,
+// trailing data following the EOM marker is considered an error. The message's previous content is replaced but
+// not modified (use Decoder to decode in place).
+func (m *Message) UnmarshalBinary(data []byte) error {
+	decoded := &Message{}
+	decodedLength, err := decodeMessage(data, decoded, false)
+	if err != nil {
+		return err
+	}
+	if decodedLength != len(data) {
+		return fmt.Errorf("unexpected trailing data: %d bytes", len(data)-decodedLength)
+	}
+	*m = *decoded
+	return nil
+}
+
+// AppendBinary encodes the header and appends it to the given buffer. The returned error is always nil.
+func (h *Header) AppendBinary(buf []byte) ([]byte, error) {
+	return h.appendBinary(buf), nil
+}
+
+// MarshalBinary encodes the header to its NSDP compliant byte representation.
+func (h *Header) MarshalBinary() ([]byte, error) {
+	return h.appendBinary(make([]byte, 0, headerLength)), nil
+}
+
+// UnmarshalBinary decodes the header from its NSDP compliant byte representation.
+func (h *Header) UnmarshalBinary(data []byte) error {
+	if len(data) != headerLength {
+		return fmt.Errorf("unexpected header length: %d", len(data))
+	}
+	decoded := &Header{}
+	err := decoded.unmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	*h = *decoded
+	return nil
+}
+
+// appendTLVBinary encodes a single TLV including its type and length prefix. Values exceeding the maximum TLV
+// length are rejected.
+func appendTLVBinary(buf []byte, tlv TLV) ([]byte, error) {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(tlv.Type()))
+	lengthOffset := len(buf)
+	buf = binary.BigEndian.AppendUint16(buf, 0)
+	buf = appendTLVValue(buf, tlv)
+	valueLength := len(buf) - lengthOffset - 2
+	if valueLength > maxTLVLength {
+		return nil, fmt.Errorf("excessive TLV length: %d (type: %04xh)", valueLength, tlv.Type())
+	}
+	binary.BigEndian.PutUint16(buf[lengthOffset:], uint16(valueLength))
+	return buf, nil
+}
+
+// unmarshalTLVBinary decodes a single TLV including its type and length prefix into the given TLV.
+func unmarshalTLVBinary(data []byte, tlvType Type, tlv tlvValueUnmarshaler) error {
+	decodedType, value, err := decodeTLVBinary(data)
+	if err != nil {
+		return err
+	}
+	if decodedType != tlvType {
+		return fmt.Errorf("unexpected TLV type: %04xh (expected: %04xh)", decodedType, tlvType)
+	}
+	return tlv.unmarshalValue(value)
+}
+
+func decodeTLVBinary(data []byte) (Type, []byte, error) {
+	tlvType, tlvLength, err := decodeMessageTLVTypeLength(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if 4+int(tlvLength) != len(data) {
+		return 0, nil, fmt.Errorf("unexpected trailing data: %d bytes", len(data)-4-int(tlvLength))
+	}
+	return Type(tlvType), data[4:], nil
+}
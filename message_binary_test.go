@@ -0,0 +1,123 @@
+// message_binary_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+type binaryCodec interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	encoding.BinaryAppender
+}
+
+func TestMessageBinary(t *testing.T) {
+	buf := mustDecodeHex(t, connTestResponseSwitch1)
+	msg := &nsdp.Message{}
+	var codec binaryCodec = msg
+	err := codec.UnmarshalBinary(buf)
+	require.NoError(t, err)
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch1), msg)
+	data, err := codec.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, buf, data)
+	require.Error(t, msg.UnmarshalBinary(append(buf, 0x00)))
+	require.Error(t, msg.UnmarshalBinary(buf[:len(buf)-1]))
+}
+
+func TestHeaderBinary(t *testing.T) {
+	header := unmarshalTestMessage(t, connTestResponseSwitch1).Header
+	var codec binaryCodec = header
+	data, err := codec.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, mustDecodeHex(t, connTestResponseSwitch1)[:32], data)
+	appended, err := codec.AppendBinary([]byte{0xff})
+	require.NoError(t, err)
+	require.Equal(t, data, appended[1:])
+	decoded := &nsdp.Header{}
+	err = decoded.UnmarshalBinary(data)
+	require.NoError(t, err)
+	require.Equal(t, header, decoded)
+	require.Error(t, decoded.UnmarshalBinary(data[:31]))
+	require.Error(t, decoded.UnmarshalBinary(append(data, 0x00)))
+}
+
+func TestTLVBinaryAllTLVs(t *testing.T) {
+	tlvs := []nsdp.TLV{
+		nsdp.NewDeviceModel("GS108Ev3"),
+		nsdp.NewDeviceName("switch1"),
+		nsdp.NewDeviceMAC(getStaticMAC()),
+		nsdp.NewDeviceLocation("Location"),
+		nsdp.NewDeviceIP(getStaticIP()),
+		nsdp.NewDeviceNetmask(getStaticIP()),
+		nsdp.NewRouterIP(getStaticIP()),
+		nsdp.NewDHCPMode(nsdp.DHCPRenew),
+		nsdp.NewFWVersionSlot1("1.2.3.4"),
+		nsdp.NewFWVersionSlot2("4.3.2.1"),
+		nsdp.NewNextFWSlot(2),
+		nsdp.NewPortStatus(1, nsdp.Link2500MFull),
+		nsdp.NewPortStatistic(1, 2, 3, 4, 5, 6, 7),
+		nsdp.NewPortCount(8),
+		nsdp.NewPowerSaving(nsdp.PowerSavingEnabled),
+		nsdp.NewLEDControl(nsdp.LEDModeOff),
+		nsdp.NewRawTLV(0x7400, []byte{0x01, 0x02, 0x03}),
+		nsdp.EmptyDeviceIP(),
+	}
+	for _, tlv := range tlvs {
+		codec, ok := tlv.(binaryCodec)
+		require.True(t, ok, "%T", tlv)
+		data, err := codec.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(tlv.Type() >> 8), byte(tlv.Type()), byte(tlv.Length() >> 8), byte(tlv.Length())}, data[:4])
+		require.Equal(t, tlv.Value(), data[4:])
+		appended, err := codec.AppendBinary([]byte{0xff})
+		require.NoError(t, err)
+		require.Equal(t, data, appended[1:])
+		decoded := reflect.New(reflect.TypeOf(tlv).Elem()).Interface().(binaryCodec)
+		err = decoded.UnmarshalBinary(data)
+		require.NoError(t, err)
+		redata, err := decoded.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, redata)
+		require.Error(t, decoded.UnmarshalBinary(append(data, 0x00)))
+		require.Error(t, decoded.UnmarshalBinary(data[:3]))
+	}
+}
+
+func TestTLVBinaryTypeMismatch(t *testing.T) {
+	data, err := nsdp.NewDeviceName("switch1").MarshalBinary()
+	require.NoError(t, err)
+	require.Error(t, nsdp.EmptyDeviceLocation().UnmarshalBinary(data))
+	raw := &nsdp.RawTLV{}
+	require.NoError(t, raw.UnmarshalBinary(data))
+	require.Equal(t, nsdp.NewRawTLV(nsdp.TypeDeviceName, []byte("switch1")), raw)
+}
+
+func TestTLVBinaryExcessiveLength(t *testing.T) {
+	tlv := nsdp.NewRawTLV(0x7400, make([]byte, 0x10000))
+	_, err := tlv.MarshalBinary()
+	require.ErrorContains(t, err, "excessive TLV length")
+	_, err = tlv.AppendBinary(nil)
+	require.ErrorContains(t, err, "excessive TLV length")
+	tlv.Data = tlv.Data[:0xffff]
+	data, err := tlv.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, 4+0xffff, len(data))
+	msg := nsdp.NewMessage(nsdp.WriteRequest)
+	msg.AppendTLV(nsdp.NewRawTLV(0x7400, make([]byte, 0x10000)))
+	_, err = msg.MarshalBinary()
+	require.ErrorContains(t, err, "excessive TLV length")
+	_, err = msg.AppendBinary(nil)
+	require.ErrorContains(t, err, "excessive TLV length")
+}
@@ -0,0 +1,120 @@
+// message_completeness.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Completeness reports the TLVs missing in a response compared to the read request it answers.
+type Completeness struct {
+	MissingTypes   []Type           // Requested TLV types missing completely in the response
+	MissingPorts   map[Type][]uint8 // Ports missing in the response for requested per-port TLV types (see PortTLV)
+	UncheckedPorts []Type           // Requested per-port TLV types whose ports could not be checked completely (as the response lacks a PortCount TLV)
+}
+
+// Complete checks whether the response contains all requested TLVs.
+//
+// A response is only considered complete, if all ports could be checked (see UncheckedPorts).
+func (completeness *Completeness) Complete() bool {
+	return len(completeness.MissingTypes) == 0 && len(completeness.MissingPorts) == 0 && len(completeness.UncheckedPorts) == 0
+}
+
+func (completeness *Completeness) String() string {
+	if completeness.Complete() {
+		return "Complete"
+	}
+	builder := &strings.Builder{}
+	builder.WriteString("Missing:")
+	for _, missingType := range completeness.MissingTypes {
+		fmt.Fprintf(builder, " %04xh", missingType)
+	}
+	for _, portType := range slices.Sorted(maps.Keys(completeness.MissingPorts)) {
+		fmt.Fprintf(builder, " %04xh%v", portType, completeness.MissingPorts[portType])
+	}
+	if len(completeness.UncheckedPorts) > 0 {
+		builder.WriteString(" Unchecked:")
+		for _, uncheckedType := range completeness.UncheckedPorts {
+			fmt.Fprintf(builder, " %04xh", uncheckedType)
+		}
+	}
+	return builder.String()
+}
+
+// CheckCompleteness compares a response with the read request it answers.
+//
+// Requested TLV types not contained in the response are reported as missing types. For per-port TLV types
+// (see PortTLV) the ports missing in the response are reported in addition. The device's port count is taken
+// from the response's PortCount TLV. If the response does not contain one (e.g. because it has not been requested),
+// only the ports below the highest port number seen in the response are checked and the per-port TLV types are
+// reported as unchecked. For requests other than read requests an empty report is returned.
+func CheckCompleteness(request *Message, response *Message) *Completeness {
+	completeness := &Completeness{
+		MissingTypes:   make([]Type, 0),
+		MissingPorts:   make(map[Type][]uint8),
+		UncheckedPorts: make([]Type, 0),
+	}
+	if request.Header.Operation != ReadRequest {
+		return completeness
+	}
+	portCount, portCountFound := responsePortCount(response)
+	for _, requested := range request.Body {
+		requestedType := requested.Type()
+		if slices.Contains(completeness.MissingTypes, requestedType) {
+			continue
+		}
+		_, perPort := requested.(PortTLV)
+		ports := make(map[uint8]bool)
+		found := false
+		for _, tlv := range response.Body {
+			if tlv.Type() != requestedType {
+				continue
+			}
+			found = true
+			portTLV, ok := tlv.(PortTLV)
+			if ok {
+				ports[portTLV.PortNumber()] = true
+			}
+		}
+		if !found {
+			completeness.MissingTypes = append(completeness.MissingTypes, requestedType)
+			continue
+		}
+		if !perPort {
+			continue
+		}
+		missingPorts := make([]uint8, 0)
+		for port := 1; port <= int(portCount); port++ {
+			if !ports[uint8(port)] {
+				missingPorts = append(missingPorts, uint8(port))
+			}
+		}
+		if len(missingPorts) > 0 {
+			completeness.MissingPorts[requestedType] = missingPorts
+		}
+		if !portCountFound && !slices.Contains(completeness.UncheckedPorts, requestedType) {
+			completeness.UncheckedPorts = append(completeness.UncheckedPorts, requestedType)
+		}
+	}
+	return completeness
+}
+
+func responsePortCount(response *Message) (uint8, bool) {
+	portCount, found := First[*PortCount](response)
+	if found {
+		return portCount.Count, true
+	}
+	var maxPort uint8
+	for _, portTLV := range All[PortTLV](response) {
+		maxPort = max(maxPort, portTLV.PortNumber())
+	}
+	return maxPort, false
+}
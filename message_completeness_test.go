@@ -0,0 +1,66 @@
+// message_completeness_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestCheckCompleteness(t *testing.T) {
+	request := nsdp.NewMessage(nsdp.ReadRequest)
+	request.AppendTLV(nsdp.EmptyDeviceName())
+	request.AppendTLV(nsdp.EmptyDeviceLocation())
+	request.AppendTLV(nsdp.EmptyPortStatus())
+	request.AppendTLV(nsdp.EmptyPortStatistic())
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.AppendTLV(nsdp.NewDeviceName("switch"))
+	response.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link1GFull))
+	response.AppendTLV(nsdp.NewPortStatus(3, nsdp.LinkDown))
+	response.AppendTLV(nsdp.NewPortStatistic(1, 0, 0, 0, 0, 0, 0))
+	response.AppendTLV(nsdp.NewPortStatistic(2, 0, 0, 0, 0, 0, 0))
+	response.AppendTLV(nsdp.NewPortStatistic(3, 0, 0, 0, 0, 0, 0))
+	completeness := nsdp.CheckCompleteness(request, response)
+	require.False(t, completeness.Complete())
+	require.Equal(t, []nsdp.Type{nsdp.TypeDeviceLocation}, completeness.MissingTypes)
+	require.Equal(t, map[nsdp.Type][]uint8{nsdp.TypePortStatus: {2}}, completeness.MissingPorts)
+	require.Equal(t, []nsdp.Type{nsdp.TypePortStatus, nsdp.TypePortStatistic}, completeness.UncheckedPorts)
+	require.Equal(t, "Missing: 0005h 0c00h[2] Unchecked: 0c00h 1000h", completeness.String())
+	response.AppendTLV(nsdp.NewPortCount(4))
+	completeness = nsdp.CheckCompleteness(request, response)
+	require.Equal(t, map[nsdp.Type][]uint8{nsdp.TypePortStatus: {2, 4}, nsdp.TypePortStatistic: {4}}, completeness.MissingPorts)
+	require.Empty(t, completeness.UncheckedPorts)
+}
+
+func TestCheckCompletenessWithoutPortCount(t *testing.T) {
+	request := nsdp.NewMessage(nsdp.ReadRequest)
+	request.AppendTLV(nsdp.EmptyPortStatus())
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link1GFull))
+	response.AppendTLV(nsdp.NewPortStatus(2, nsdp.LinkDown))
+	completeness := nsdp.CheckCompleteness(request, response)
+	require.Empty(t, completeness.MissingTypes)
+	require.Empty(t, completeness.MissingPorts)
+	require.False(t, completeness.Complete())
+	request.AppendTLV(nsdp.EmptyPortCount())
+	response.AppendTLV(nsdp.NewPortCount(2))
+	completeness = nsdp.CheckCompleteness(request, response)
+	require.True(t, completeness.Complete())
+}
+
+func TestCheckCompletenessComplete(t *testing.T) {
+	request := nsdp.NewMessage(nsdp.ReadRequest)
+	request.AppendTLV(nsdp.EmptyDeviceName())
+	response := nsdp.NewMessage(nsdp.ReadResponse)
+	response.AppendTLV(nsdp.NewDeviceName("switch"))
+	completeness := nsdp.CheckCompleteness(request, response)
+	require.True(t, completeness.Complete())
+	require.Equal(t, "Complete", completeness.String())
+}
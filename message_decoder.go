@@ -0,0 +1,29 @@
+// message_decoder.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+// Decoder decodes NSDP byte streams into existing messages.
+//
+// In contrast to UnmarshalMessage, the decoder re-uses the storage of the target message. The header, the body
+// slice as well as TLVs matching the type of the TLV decoded at the same position are updated in place. Hence
+// any reference retained to one of these elements observes the updates of subsequent decode calls. TLVs of a
+// custom type are decoded via their registered decoder (see RegisterTLV) and are always created anew.
+//
+// Decoding into a warmed up message (a message having received a similar byte stream before) does not
+// allocate any memory for the built-in TLV types.
+type Decoder struct {
+	Strict bool // Rejects TLVs of unrecognized type (see UnmarshalMessageStrict)
+}
+
+// Decode decodes the given NSDP byte stream into the given message.
+//
+// If decoding fails, the message is left in an undefined state.
+func (d *Decoder) Decode(buf []byte, msg *Message) error {
+	_, err := decodeMessage(buf, msg, d.Strict)
+	return err
+}
@@ -0,0 +1,134 @@
+// message_decoder_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestDecoderDecode(t *testing.T) {
+	buf := mustDecodeHex(t, connTestResponseSwitch1)
+	decoder := &nsdp.Decoder{}
+	msg := &nsdp.Message{}
+	err := decoder.Decode(buf, msg)
+	require.NoError(t, err)
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch1), msg)
+	require.Equal(t, buf, msg.Marshal())
+}
+
+func TestDecoderDecodeReuse(t *testing.T) {
+	decoder := &nsdp.Decoder{}
+	msg := &nsdp.Message{}
+	err := decoder.Decode(mustDecodeHex(t, connTestResponseSwitch1), msg)
+	require.NoError(t, err)
+	header := msg.Header
+	deviceName := msg.Body[1]
+	err = decoder.Decode(mustDecodeHex(t, connTestResponseSwitch2), msg)
+	require.NoError(t, err)
+	require.Same(t, header, msg.Header)
+	require.Same(t, deviceName, msg.Body[1])
+	require.Equal(t, nsdp.NewDeviceName("switch2"), deviceName)
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch2), msg)
+}
+
+func TestDecoderDecodeTypeMismatch(t *testing.T) {
+	decoder := &nsdp.Decoder{}
+	msg := nsdp.NewMessage(nsdp.ReadResponse)
+	msg.AppendTLV(nsdp.NewDeviceName("switch"))
+	msg.AppendTLV(nsdp.NewRawTLV(0x7401, []byte{0x01}))
+	msg.AppendTLV(nsdp.NewDeviceLocation("location"))
+	encoded := nsdp.NewMessage(nsdp.ReadResponse)
+	encoded.AppendTLV(nsdp.NewDeviceModel("model"))
+	encoded.AppendTLV(nsdp.NewRawTLV(0x7402, []byte{0x02}))
+	err := decoder.Decode(encoded.Marshal(), msg)
+	require.NoError(t, err)
+	require.Equal(t, encoded.Body, msg.Body)
+}
+
+func TestDecoderDecodeStrict(t *testing.T) {
+	encoded := nsdp.NewMessage(nsdp.ReadResponse)
+	encoded.AppendTLV(nsdp.NewRawTLV(0x7401, []byte{0x01}))
+	decoder := &nsdp.Decoder{Strict: true}
+	err := decoder.Decode(encoded.Marshal(), &nsdp.Message{})
+	require.Error(t, err)
+}
+
+func TestDecoderDecodeNoAlias(t *testing.T) {
+	buf := mustDecodeHex(t, connTestResponseSwitch1)
+	msg := &nsdp.Message{}
+	err := (&nsdp.Decoder{}).Decode(buf, msg)
+	require.NoError(t, err)
+	clear(buf)
+	require.Equal(t, unmarshalTestMessage(t, connTestResponseSwitch1), msg)
+}
+
+func TestDecoderDecodeAllocs(t *testing.T) {
+	buf := mustDecodeHex(t, connTestResponseSwitch1)
+	decoder := &nsdp.Decoder{}
+	msg := &nsdp.Message{}
+	require.NoError(t, decoder.Decode(buf, msg))
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = decoder.Decode(buf, msg)
+	})
+	require.Zero(t, allocs)
+}
+
+func TestMessageAppendBinary(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	buf, err := msg.AppendBinary([]byte{0xff})
+	require.NoError(t, err)
+	require.Equal(t, byte(0xff), buf[0])
+	require.Equal(t, msg.Marshal(), buf[1:])
+}
+
+func TestMessageAppendBinaryAllocs(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	buf, _ := msg.AppendBinary(nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = msg.AppendBinary(buf[:0])
+	})
+	require.Zero(t, allocs)
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	msg := unmarshalTestMessage(b, connTestResponseSwitch1)
+	b.ReportAllocs()
+	for b.Loop() {
+		msg.Marshal()
+	}
+}
+
+func BenchmarkAppendBinary(b *testing.B) {
+	msg := unmarshalTestMessage(b, connTestResponseSwitch1)
+	var buf []byte
+	b.ReportAllocs()
+	for b.Loop() {
+		buf, _ = msg.AppendBinary(buf[:0])
+	}
+}
+
+func BenchmarkUnmarshalMessage(b *testing.B) {
+	buf := mustDecodeHex(b, connTestResponseSwitch1)
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = nsdp.UnmarshalMessage(buf)
+	}
+}
+
+func BenchmarkDecoderDecode(b *testing.B) {
+	buf := mustDecodeHex(b, connTestResponseSwitch1)
+	decoder := &nsdp.Decoder{}
+	msg := &nsdp.Message{}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = decoder.Decode(buf, msg)
+	}
+}
@@ -0,0 +1,230 @@
+// message_dump.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DumpMessage formats the given NSDP byte stream as an annotated hex dump.
+//
+// Each output line lists the offset and the raw bytes of a single header field or TLV element together with
+// its decoded meaning. Fields longer than 16 bytes are continued on the following lines. If the byte stream
+// is malformed, the byte causing the decoding failure is marked and the remaining bytes are dumped without
+// annotation. TLVs of unrecognized type are annotated as RawTLV elements (see UnmarshalMessage).
+func DumpMessage(buf []byte) string {
+	return dumpMessage(buf, false)
+}
+
+const dumpBytesPerLine int = 16
+
+type dumpRange struct {
+	offset int
+	length int
+	label  string
+}
+
+type dumpFailure struct {
+	offset int
+	err    error
+}
+
+type messageDumper struct {
+	buf       []byte
+	operation OperationCode
+	offset    int
+	ranges    []dumpRange
+	failure   *dumpFailure
+}
+
+func dumpMessage(buf []byte, strict bool) string {
+	dumper := &messageDumper{buf: buf}
+	dumper.dumpHeader()
+	dumper.dumpBody(strict)
+	if dumper.offset < len(buf) {
+		label := "(not decoded)"
+		if dumper.failure == nil {
+			label = "(trailing data)"
+		}
+		dumper.add(len(buf)-dumper.offset, label)
+	}
+	return dumper.format()
+}
+
+type dumpHeaderField struct {
+	name   string
+	length int
+	check  func(value []byte) error
+	format func(value []byte) string
+}
+
+var dumpHeaderFields = []dumpHeaderField{
+	{name: "Version", length: 1, check: checkDumpVersion, format: formatDumpHex},
+	{name: "Operation", length: 1, check: checkDumpOperation, format: formatDumpHex},
+	{name: "Result", length: 2, format: formatDumpHex},
+	{name: "Unknown1", length: 4, format: formatDumpHex},
+	{name: "HostAddress", length: 6, format: formatDumpMAC},
+	{name: "DeviceAddress", length: 6, format: formatDumpMAC},
+	{name: "Unknown2", length: 2, format: formatDumpHex},
+	{name: "Sequence", length: 2, format: formatDumpHex},
+	{name: "Signature", length: 4, check: checkDumpSignature, format: formatDumpHex},
+	{name: "Unknown3", length: 4, format: formatDumpHex},
+}
+
+func (dumper *messageDumper) dumpHeader() {
+	for _, field := range dumpHeaderFields {
+		value, ok := dumper.take(field.length, "Header."+field.name)
+		if !ok {
+			return
+		}
+		if field.check != nil {
+			err := field.check(value)
+			if err != nil {
+				dumper.fail(dumper.offset-field.length, err)
+				return
+			}
+		}
+		dumper.relabel("Header." + field.name + ": " + field.format(value))
+		if field.name == "Operation" {
+			dumper.operation = OperationCode(value[0])
+		}
+	}
+}
+
+func (dumper *messageDumper) dumpBody(strict bool) {
+	for i := 0; dumper.failure == nil; i++ {
+		typeValue, ok := dumper.take(2, fmt.Sprintf("TLV[%d] type", i))
+		if !ok {
+			return
+		}
+		tlvType := binary.BigEndian.Uint16(typeValue)
+		if tlvType == uint16(TypeEOM) {
+			dumper.relabel("EOM marker: ffffh")
+		} else {
+			dumper.relabel(fmt.Sprintf("TLV[%d] type: %04xh", i, tlvType))
+		}
+		lengthValue, ok := dumper.take(2, fmt.Sprintf("TLV[%d] length", i))
+		if !ok {
+			return
+		}
+		tlvLength := int(binary.BigEndian.Uint16(lengthValue))
+		if tlvType == uint16(TypeEOM) {
+			dumper.relabel(fmt.Sprintf("EOM marker: %04xh", tlvLength))
+			if tlvLength != 0 {
+				dumper.fail(dumper.offset-2, fmt.Errorf("unexpected EOM marker: %04x%04xh", tlvType, tlvLength))
+			}
+			return
+		}
+		dumper.relabel(fmt.Sprintf("TLV[%d] length: %d", i, tlvLength))
+		if tlvLength > len(dumper.buf)-dumper.offset {
+			dumper.fail(dumper.offset-2, fmt.Errorf("excessive TLV length: %d (remaining: %d)", tlvLength, len(dumper.buf)-dumper.offset))
+			return
+		}
+		tlvValue := dumper.buf[dumper.offset : dumper.offset+tlvLength]
+		if dumper.operation == ReadRequest {
+			tlvValue = nil
+		}
+		tlv, err := unmarshalTLV(tlvType, tlvValue)
+		if !strict && errors.Is(err, errUnrecognizedTLVType) {
+			tlv, err = NewRawTLV(Type(tlvType), tlvValue), nil
+		}
+		if err != nil {
+			dumper.fail(dumper.offset, fmt.Errorf("error while decoding TLV type %04xh; cause: %v", tlvType, err))
+			return
+		}
+		if tlvLength > 0 && dumper.operation == ReadRequest {
+			dumper.add(tlvLength, fmt.Sprintf("TLV[%d] %s (value ignored in read request)", i, tlv))
+		} else if tlvLength > 0 {
+			dumper.add(tlvLength, fmt.Sprintf("TLV[%d] %s", i, tlv))
+		}
+	}
+}
+
+func (dumper *messageDumper) take(length int, label string) ([]byte, bool) {
+	available := min(length, len(dumper.buf)-dumper.offset)
+	if available > 0 {
+		dumper.add(available, label)
+	}
+	if available < length {
+		dumper.fail(len(dumper.buf), fmt.Errorf("unexpected end of data while decoding %s", label))
+		return nil, false
+	}
+	return dumper.buf[dumper.offset-length : dumper.offset], true
+}
+
+func (dumper *messageDumper) add(length int, label string) {
+	dumper.ranges = append(dumper.ranges, dumpRange{offset: dumper.offset, length: length, label: label})
+	dumper.offset += length
+}
+
+func (dumper *messageDumper) relabel(label string) {
+	dumper.ranges[len(dumper.ranges)-1].label = label
+}
+
+func (dumper *messageDumper) fail(offset int, err error) {
+	dumper.failure = &dumpFailure{offset: offset, err: err}
+}
+
+func (dumper *messageDumper) format() string {
+	builder := &strings.Builder{}
+	for _, r := range dumper.ranges {
+		for lineOffset := r.offset; lineOffset < r.offset+r.length; lineOffset += dumpBytesPerLine {
+			lineLength := min(dumpBytesPerLine, r.offset+r.length-lineOffset)
+			fmt.Fprintf(builder, "%04x ", lineOffset)
+			for _, b := range dumper.buf[lineOffset : lineOffset+lineLength] {
+				fmt.Fprintf(builder, " %02x", b)
+			}
+			if lineOffset == r.offset {
+				builder.WriteString(strings.Repeat("   ", dumpBytesPerLine-lineLength))
+				builder.WriteString("  ")
+				builder.WriteString(r.label)
+			}
+			builder.WriteRune('\n')
+			if dumper.failure != nil && lineOffset <= dumper.failure.offset && dumper.failure.offset < lineOffset+lineLength {
+				dumper.writeFailure(builder, dumper.failure.offset-lineOffset)
+			}
+		}
+	}
+	if dumper.failure != nil && dumper.failure.offset == len(dumper.buf) {
+		fmt.Fprintf(builder, "%04x  ", dumper.failure.offset)
+		dumper.writeFailure(builder, 0)
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+func (dumper *messageDumper) writeFailure(builder *strings.Builder, column int) {
+	if dumper.failure.offset < len(dumper.buf) {
+		builder.WriteString("      ")
+	}
+	builder.WriteString(strings.Repeat("   ", column))
+	fmt.Fprintf(builder, "^^ decoding failed at offset %04xh: %v\n", dumper.failure.offset, dumper.failure.err)
+}
+
+func checkDumpVersion(value []byte) error {
+	return checkHeaderVersion(ProtoVersion(value[0]))
+}
+
+func checkDumpOperation(value []byte) error {
+	return checkHeaderOperation(OperationCode(value[0]))
+}
+
+func checkDumpSignature(value []byte) error {
+	return checkHeaderSignature(Signature(binary.BigEndian.Uint32(value)))
+}
+
+func formatDumpHex(value []byte) string {
+	return fmt.Sprintf("%xh", value)
+}
+
+func formatDumpMAC(value []byte) string {
+	return net.HardwareAddr(value).String()
+}
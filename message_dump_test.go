@@ -0,0 +1,58 @@
+// message_dump_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestDumpMessage(t *testing.T) {
+	buf, err := hex.DecodeString(connTestResponseSwitch1)
+	require.NoError(t, err)
+	dump := nsdp.DumpMessage(buf)
+	require.NotContains(t, dump, "^^")
+	lines := strings.Split(dump, "\n")
+	require.Equal(t, "0000  01                                               Header.Version: 01h", lines[0])
+	require.Equal(t, "0008  bc d0 74 32 b8 dc                                Header.HostAddress: bc:d0:74:32:b8:dc", lines[4])
+	require.Equal(t, "0024  47 53 31 30 38 45 76 33                          TLV[0] DeviceModel(0001h) 'GS108Ev3'", lines[12])
+	require.Contains(t, dump, "00b2  01 00 00 00 01 1b 86 e2 c2 00 00 00 00 0d 15 9e  TLV[19] PortStatistic(1000h) Port1 Received: 4756792002, Sent: 219520568, Packets: 0, Broadcasts: 0, Multicasts: 0, Errors: 0\n00c2  38 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00\n")
+	require.Equal(t, "0256  ff ff                                            EOM marker: ffffh", lines[len(lines)-2])
+	require.Equal(t, "0258  00 00                                            EOM marker: 0000h", lines[len(lines)-1])
+}
+
+func TestDumpMessageMalformed(t *testing.T) {
+	msg := nsdp.NewMessage(nsdp.ReadResponse)
+	msg.AppendTLV(nsdp.NewDeviceModel("GS108Ev3"))
+	msg.AppendTLV(nsdp.NewDeviceIP(getStaticIP()))
+	valid := msg.Marshal()
+	// Unrecognized operation
+	invalidOperation := append([]byte{}, valid...)
+	invalidOperation[1] = 0x09
+	runDumpMessageFailureTest(t, invalidOperation, "0001  09                                               Header.Operation\n      ^^ decoding failed at offset 0001h: unrecognized operation code: 09h")
+	// Invalid DeviceIP length
+	invalidValue := append([]byte{}, valid...)
+	invalidValue[0x2f] = 0x03
+	runDumpMessageFailureTest(t, invalidValue, "0030  01 02 03 04 ff ff 00 00                          (not decoded)")
+	runDumpMessageFailureTest(t, invalidValue, "      ^^ decoding failed at offset 0030h: error while decoding TLV type 0006h")
+	// Truncated data
+	runDumpMessageFailureTest(t, valid[:len(valid)-3], "0034  ff                                               TLV[2] type\n0035  ^^ decoding failed at offset 0035h: unexpected end of data while decoding TLV[2] type")
+	// Trailing data
+	require.True(t, strings.HasSuffix(nsdp.DumpMessage(append(valid, 0x01)), "0038  01                                               (trailing data)"))
+}
+
+func runDumpMessageFailureTest(t *testing.T, buf []byte, expected string) {
+	_, err := nsdp.UnmarshalMessage(buf)
+	require.Error(t, err)
+	dump := nsdp.DumpMessage(buf)
+	require.Contains(t, dump, expected, dump)
+}
@@ -8,7 +8,6 @@
 package nsdp
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"strings"
@@ -35,6 +34,6 @@ func (m *EOM) writeString(builder *strings.Builder) {
 	fmt.Fprintf(builder, "EOM   : %08xh", m.Marker)
 }
 
-func (m *EOM) marshalBuffer(buffer *bytes.Buffer) {
-	binary.Write(buffer, binary.BigEndian, m.Marker)
+func (m *EOM) appendBinary(buf []byte) []byte {
+	return binary.BigEndian.AppendUint32(buf, m.Marker)
 }
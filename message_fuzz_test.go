@@ -0,0 +1,80 @@
+// message_fuzz_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func FuzzUnmarshalMessage(f *testing.F) {
+	for _, fixture := range []string{connTestResponseSwitch1, connTestResponseSwitch2, connTestResponseSwitch2Unicast} {
+		f.Add(mustDecodeHex(f, fixture))
+	}
+	f.Add(prepareTestMessage().Marshal())
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		msg, err := nsdp.UnmarshalMessage(buf)
+		// The dump must mark exactly those byte streams failing to decode
+		require.Equal(t, err != nil, strings.Contains(nsdp.DumpMessage(buf), "^^ decoding failed"))
+		if err != nil {
+			require.Nil(t, msg)
+			return
+		}
+		// Encoding a decoded message must result in a stable byte stream
+		encoded := msg.Marshal()
+		decoded, err := nsdp.UnmarshalMessage(encoded)
+		require.NoError(t, err)
+		require.Equal(t, encoded, decoded.Marshal())
+		require.Equal(t, msg.String(), decoded.String())
+		// Strict decoding must either fail or yield the same result
+		strict, err := nsdp.UnmarshalMessageStrict(buf)
+		if err == nil {
+			require.Equal(t, encoded, strict.Marshal())
+		}
+	})
+}
+
+func FuzzUnmarshalTLV(f *testing.F) {
+	msg, err := nsdp.UnmarshalMessage(mustDecodeHex(f, connTestResponseSwitch1))
+	require.NoError(f, err)
+	for _, tlv := range msg.Body {
+		f.Add(uint16(tlv.Type()), tlv.Value())
+	}
+	for _, tlvType := range nsdp.RegisteredTLVTypes() {
+		f.Add(uint16(tlvType), []byte{})
+	}
+	f.Fuzz(func(t *testing.T, tlvType uint16, value []byte) {
+		decoder, found := nsdp.LookupTLVDecoder(nsdp.Type(tlvType))
+		if !found {
+			return
+		}
+		tlv, err := decoder(value)
+		if err != nil {
+			return
+		}
+		require.Equal(t, nsdp.Type(tlvType), tlv.Type())
+		require.Equal(t, int(tlv.Length()), len(tlv.Value()))
+		// Re-decoding an encoded TLV must result in a stable value
+		encoded := tlv.Value()
+		decoded, err := decoder(encoded)
+		require.NoError(t, err)
+		require.Equal(t, encoded, decoded.Value())
+		require.Equal(t, fmt.Sprint(tlv), fmt.Sprint(decoded))
+	})
+}
+
+func mustDecodeHex(tb testing.TB, s string) []byte {
+	buf, err := hex.DecodeString(s)
+	require.NoError(tb, err)
+	return buf
+}
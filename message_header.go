@@ -8,9 +8,9 @@
 package nsdp
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 )
@@ -19,16 +19,16 @@ import (
 //
 // The header defines the type of operation (Operation) and the targeted device (DeviceAddress).
 type Header struct {
-	Version       ProtoVersion    // Always 1 (see ProtoVersion type)
-	Operation     OperationCode   // One of read-request, read-response, write-request or write response (see OperationCode type)
-	Result        OperationResult // The actual message processing result (0 indicating success)
-	Unknown1      uint32
+	Version       ProtoVersion     // Always 1 (see ProtoVersion type)
+	Operation     OperationCode    // One of read-request, read-response, write-request or write response (see OperationCode type)
+	Result        OperationResult  // The actual message processing result (0 indicating success)
+	Unknown1      uint32           // Unknown (upper half presumably carries the offending TLV type of error responses; see ErrorTLV)
 	HostAddress   net.HardwareAddr // MAC of the sending device (is handled automatically during message processing)
 	DeviceAddress net.HardwareAddr // MAC of the target device (keeping the default 00:00:00:00:00:00 addresses all devices)
-	Unknown2      uint16
-	Sequence      Sequence  // Used to identify/verify a request-response sequence (is handled automatically during message processing)
-	Signature     Signature // NSDP signature (should not be changed)
-	Unknown3      uint32
+	Unknown2      uint16           // Unknown
+	Sequence      Sequence         // Used to identify/verify a request-response sequence (is handled automatically during message processing)
+	Signature     Signature        // NSDP signature (should not be changed)
+	Unknown3      uint32           // Reserved (always 0 in observed traffic)
 }
 
 type ProtoVersion uint8
@@ -81,80 +81,95 @@ func (h *Header) prepareHeader(hostAddress net.HardwareAddr, sequence Sequence)
 	}
 }
 
+// ErrorTLV gets the offending TLV type of an error response.
+//
+// This is a heuristic interpretation of the otherwise undocumented Unknown1 field (not yet backed by a captured error
+// response): devices seem to report the TLV type causing a non-zero result in its upper half. If the header does not
+// report a failure (Result is 0), 0 is returned.
+func (h *Header) ErrorTLV() Type {
+	if h.Result == ResultSuccess {
+		return 0
+	}
+	return Type(h.Unknown1 >> 16)
+}
+
+// SetErrorTLV sets the offending TLV type of an error response (leaving the lower half of the Unknown1 field unchanged).
+func (h *Header) SetErrorTLV(tlvType Type) {
+	h.Unknown1 = uint32(tlvType)<<16 | h.Unknown1&0x0000ffff
+}
+
 func (h *Header) writeString(builder *strings.Builder) {
 	fmt.Fprintf(builder, "Header: %02xh %02xh %04xh %08xh %s %s %04xh %04xh %08xh", h.Version, h.Operation, h.Result, h.Unknown1, h.HostAddress.String(), h.DeviceAddress.String(), h.Unknown2, h.Sequence, h.Signature)
 }
 
-func (h *Header) marshalBuffer(buffer *bytes.Buffer) {
-	buffer.WriteByte(byte(h.Version))
-	buffer.WriteByte(byte(h.Operation))
-	binary.Write(buffer, binary.BigEndian, h.Result)
-	binary.Write(buffer, binary.BigEndian, h.Unknown1)
-	buffer.Write(h.HostAddress)
-	buffer.Write(h.DeviceAddress)
-	binary.Write(buffer, binary.BigEndian, h.Unknown2)
-	binary.Write(buffer, binary.BigEndian, h.Sequence)
-	binary.Write(buffer, binary.BigEndian, h.Signature)
-	binary.Write(buffer, binary.BigEndian, h.Unknown3)
+const headerLength int = 32
+
+func (h *Header) appendBinary(buf []byte) []byte {
+	buf = append(buf, byte(h.Version), byte(h.Operation))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(h.Result))
+	buf = binary.BigEndian.AppendUint32(buf, h.Unknown1)
+	buf = append(buf, h.HostAddress...)
+	buf = append(buf, h.DeviceAddress...)
+	buf = binary.BigEndian.AppendUint16(buf, h.Unknown2)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(h.Sequence))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(h.Signature))
+	return binary.BigEndian.AppendUint32(buf, h.Unknown3)
 }
 
-func unmarshalHeaderBuffer(buffer *bytes.Buffer) (*Header, error) {
-	header := &Header{
-		HostAddress:   make([]byte, 6),
-		DeviceAddress: make([]byte, 6),
-	}
-	version, err := buffer.ReadByte()
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding proto version; cause: %v", err)
-	}
-	if version != uint8(ProtoVersion1) {
-		return nil, fmt.Errorf("unrecognized proto version: %02xh", version)
-	}
-	header.Version = ProtoVersion(version)
-	operation, err := buffer.ReadByte()
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding operation code; cause: %v", err)
-	}
-	if operation != uint8(ReadRequest) && operation != uint8(ReadResponse) && operation != uint8(WriteRequest) && operation != uint8(WriteResponse) {
-		return nil, fmt.Errorf("unrecognized operation code: %04xh", operation)
-	}
-	header.Operation = OperationCode(operation)
-	err = binary.Read(buffer, binary.BigEndian, &header.Result)
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding result code; cause: %v", err)
+func (h *Header) unmarshalBinary(buf []byte) error {
+	if len(buf) < headerLength {
+		return fmt.Errorf("error while decoding header; cause: %v", io.ErrUnexpectedEOF)
 	}
-	err = binary.Read(buffer, binary.BigEndian, &header.Unknown1)
+	version := buf[0]
+	operation := buf[1]
+	signature := binary.BigEndian.Uint32(buf[24:28])
+	err := checkHeaderFields(ProtoVersion(version), OperationCode(operation), Signature(signature))
 	if err != nil {
-		return nil, fmt.Errorf("error while decoding unknown1; cause: %v", err)
-	}
-	err = binary.Read(buffer, binary.BigEndian, header.HostAddress)
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding host address; cause: %v", err)
-	}
-	err = binary.Read(buffer, binary.BigEndian, header.DeviceAddress)
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding device address; cause: %v", err)
-	}
-	err = binary.Read(buffer, binary.BigEndian, &header.Unknown2)
+		return err
+	}
+	h.Version = ProtoVersion(version)
+	h.Operation = OperationCode(operation)
+	h.Result = OperationResult(binary.BigEndian.Uint16(buf[2:4]))
+	h.Unknown1 = binary.BigEndian.Uint32(buf[4:8])
+	h.HostAddress = append(h.HostAddress[:0], buf[8:14]...)
+	h.DeviceAddress = append(h.DeviceAddress[:0], buf[14:20]...)
+	h.Unknown2 = binary.BigEndian.Uint16(buf[20:22])
+	h.Sequence = Sequence(binary.BigEndian.Uint16(buf[22:24]))
+	h.Signature = Signature(signature)
+	h.Unknown3 = binary.BigEndian.Uint32(buf[28:32])
+	return nil
+}
+
+// checkHeaderFields checks the header fields with a fixed set of valid values.
+func checkHeaderFields(version ProtoVersion, operation OperationCode, signature Signature) error {
+	err := checkHeaderVersion(version)
 	if err != nil {
-		return nil, fmt.Errorf("error while decoding unknown2; cause: %v", err)
+		return err
 	}
-	err = binary.Read(buffer, binary.BigEndian, &header.Sequence)
+	err = checkHeaderOperation(operation)
 	if err != nil {
-		return nil, fmt.Errorf("error while decoding sequence; cause: %v", err)
+		return err
 	}
-	var signature uint32
-	err = binary.Read(buffer, binary.BigEndian, &signature)
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding signature; cause: %v", err)
+	return checkHeaderSignature(signature)
+}
+
+func checkHeaderVersion(version ProtoVersion) error {
+	if version != ProtoVersion1 {
+		return fmt.Errorf("unrecognized proto version: %02xh", uint8(version))
 	}
-	if signature != uint32(NSDPSignature) {
-		return nil, fmt.Errorf("unrecognized signature: %08xh", signature)
+	return nil
+}
+
+func checkHeaderOperation(operation OperationCode) error {
+	if operation != ReadRequest && operation != ReadResponse && operation != WriteRequest && operation != WriteResponse {
+		return fmt.Errorf("unrecognized operation code: %02xh", uint8(operation))
 	}
-	header.Signature = Signature(signature)
-	err = binary.Read(buffer, binary.BigEndian, &header.Unknown3)
-	if err != nil {
-		return nil, fmt.Errorf("error while decoding unknown3; cause: %v", err)
+	return nil
+}
+
+func checkHeaderSignature(signature Signature) error {
+	if signature != NSDPSignature {
+		return fmt.Errorf("unrecognized signature: %08xh", uint32(signature))
 	}
-	return header, nil
+	return nil
 }
@@ -0,0 +1,57 @@
+// message_header_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestHeaderErrorTLVCaptured(t *testing.T) {
+	file, err := os.Open("testdata/discovery.pcap")
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := nsdp.NewCaptureReader(file)
+	require.NoError(t, err)
+	datagrams, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, datagrams)
+	msgs := []*nsdp.Message{
+		unmarshalTestMessage(t, connTestResponseSwitch1),
+		unmarshalTestMessage(t, connTestResponseSwitch2),
+	}
+	for _, datagram := range datagrams {
+		msgs = append(msgs, datagram.Message)
+	}
+	for _, msg := range msgs {
+		require.Equal(t, nsdp.ResultSuccess, msg.Header.Result)
+		require.Equal(t, nsdp.Type(0), msg.Header.ErrorTLV())
+		require.NoError(t, msg.Err())
+	}
+}
+
+func TestHeaderErrorTLV(t *testing.T) {
+	msg := nsdp.NewMessage(nsdp.WriteResponse)
+	msg.Header.Unknown1 = 0x00001234
+	msg.Header.SetErrorTLV(nsdp.TypeDeviceName)
+	require.Equal(t, uint32(0x00031234), msg.Header.Unknown1)
+	// Not reported as long as the result indicates success
+	require.Equal(t, nsdp.Type(0), msg.Header.ErrorTLV())
+	require.NoError(t, msg.Err())
+	msg.Header.Result = nsdp.ResultInvalidValue
+	unmarshaled, err := nsdp.UnmarshalMessage(msg.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, nsdp.TypeDeviceName, unmarshaled.Header.ErrorTLV())
+	require.ErrorIs(t, unmarshaled.Err(), nsdp.ErrInvalidValue)
+	var resultErr *nsdp.ResultError
+	require.ErrorAs(t, unmarshaled.Err(), &resultErr)
+	require.Equal(t, nsdp.TypeDeviceName, resultErr.TLV)
+}
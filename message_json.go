@@ -0,0 +1,243 @@
+// message_json.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+type messageJSON struct {
+	Header *Header           `json:"header"`
+	Body   []json.RawMessage `json:"body"`
+}
+
+// MarshalJSON encodes the message to JSON.
+//
+// Each TLV is encoded as a JSON object tagged with the TLV's type code (e.g. {"type":3,"name":"switch1"}).
+// TLVs not supporting JSON encoding themselves are encoded like a RawTLV (e.g. {"type":29696,"data":"0102"}).
+func (m *Message) MarshalJSON() ([]byte, error) {
+	body := make([]json.RawMessage, 0, len(m.Body))
+	for _, tlv := range m.Body {
+		marshaler, ok := tlv.(json.Marshaler)
+		if !ok {
+			marshaler = NewRawTLV(tlv.Type(), tlv.Value())
+		}
+		data, err := marshaler.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode TLV type %04xh; cause: %w", tlv.Type(), err)
+		}
+		body = append(body, data)
+	}
+	return json.Marshal(&messageJSON{Header: m.Header, Body: body})
+}
+
+// UnmarshalJSON decodes the message from JSON.
+//
+// The concrete TLVs are rebuild using the type codes the TLVs are tagged with. TLVs of unrecognized type as well as
+// registered TLVs not supporting JSON decoding are decoded via their raw data (see RawTLV).
+func (m *Message) UnmarshalJSON(data []byte) error {
+	decoded := &messageJSON{}
+	err := json.Unmarshal(data, decoded)
+	if err != nil {
+		return err
+	}
+	if decoded.Header == nil {
+		return fmt.Errorf("missing message header")
+	}
+	body := make([]TLV, 0, len(decoded.Body))
+	for i, tlvData := range decoded.Body {
+		tlv, err := unmarshalTLVJSONValue(tlvData)
+		if err != nil {
+			return fmt.Errorf("failed to decode TLV[%d]; cause: %w", i, err)
+		}
+		body = append(body, tlv)
+	}
+	m.Header = decoded.Header
+	m.Body = body
+	m.EOM = newEOM()
+	return nil
+}
+
+func unmarshalTLVJSONValue(data []byte) (TLV, error) {
+	tlvType, err := peekTLVJSONType(data)
+	if err != nil {
+		return nil, err
+	}
+	tlv := newEmptyTLV(tlvType)
+	_, isRaw := tlv.(*RawTLV)
+	unmarshaler, ok := tlv.(json.Unmarshaler)
+	if ok && !isRaw {
+		err = unmarshaler.UnmarshalJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		return tlv, nil
+	}
+	rawTLV := &RawTLV{}
+	err = rawTLV.UnmarshalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	decodedTLV, err := unmarshalTLV(uint16(rawTLV.RawType), rawTLV.Data)
+	if err != nil {
+		return rawTLV, nil
+	}
+	return decodedTLV, nil
+}
+
+type headerJSON struct {
+	Version       ProtoVersion    `json:"version"`
+	Operation     OperationCode   `json:"operation"`
+	Result        OperationResult `json:"result"`
+	Unknown1      uint32          `json:"unknown1"`
+	HostAddress   string          `json:"hostAddress"`
+	DeviceAddress string          `json:"deviceAddress"`
+	Unknown2      uint16          `json:"unknown2"`
+	Sequence      Sequence        `json:"sequence"`
+	Signature     Signature       `json:"signature"`
+	Unknown3      uint32          `json:"unknown3"`
+}
+
+// MarshalJSON encodes the header to JSON.
+func (h *Header) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&headerJSON{
+		Version:       h.Version,
+		Operation:     h.Operation,
+		Result:        h.Result,
+		Unknown1:      h.Unknown1,
+		HostAddress:   h.HostAddress.String(),
+		DeviceAddress: h.DeviceAddress.String(),
+		Unknown2:      h.Unknown2,
+		Sequence:      h.Sequence,
+		Signature:     h.Signature,
+		Unknown3:      h.Unknown3,
+	})
+}
+
+// UnmarshalJSON decodes the header from JSON.
+//
+// Both addresses are mandatory and Version, Operation as well as Signature must carry valid values, to ensure the
+// decoded header can be encoded into a valid NSDP message.
+func (h *Header) UnmarshalJSON(data []byte) error {
+	decoded := &headerJSON{}
+	err := json.Unmarshal(data, decoded)
+	if err != nil {
+		return err
+	}
+	err = checkHeaderFields(decoded.Version, decoded.Operation, decoded.Signature)
+	if err != nil {
+		return err
+	}
+	hostAddress, err := parseJSONHeaderMAC("host", decoded.HostAddress)
+	if err != nil {
+		return err
+	}
+	deviceAddress, err := parseJSONHeaderMAC("device", decoded.DeviceAddress)
+	if err != nil {
+		return err
+	}
+	h.Version = decoded.Version
+	h.Operation = decoded.Operation
+	h.Result = decoded.Result
+	h.Unknown1 = decoded.Unknown1
+	h.HostAddress = hostAddress
+	h.DeviceAddress = deviceAddress
+	h.Unknown2 = decoded.Unknown2
+	h.Sequence = decoded.Sequence
+	h.Signature = decoded.Signature
+	h.Unknown3 = decoded.Unknown3
+	return nil
+}
+
+func parseJSONHeaderMAC(name string, text string) (net.HardwareAddr, error) {
+	if text == "" {
+		return nil, fmt.Errorf("missing %s address", name)
+	}
+	return parseJSONMAC(text)
+}
+
+func parseJSONMAC(text string) (net.HardwareAddr, error) {
+	if text == "" {
+		return net.HardwareAddr{}, nil
+	}
+	mac, err := net.ParseMAC(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("unexpected MAC length: %s", text)
+	}
+	return mac, nil
+}
+
+func formatJSONIP(ip net.IP) string {
+	if len(ip) == 0 {
+		return ""
+	}
+	return ip.String()
+}
+
+func parseJSONIP(text string) (net.IP, error) {
+	if text == "" {
+		return net.IP{}, nil
+	}
+	ip := net.ParseIP(text)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP: %s", text)
+	}
+	ip4 := ip.To4()
+	if ip4 != nil {
+		return ip4, nil
+	}
+	return ip, nil
+}
+
+type tlvJSONType struct {
+	Type *Type `json:"type"`
+}
+
+func peekTLVJSONType(data []byte) (Type, error) {
+	decoded := &tlvJSONType{}
+	err := json.Unmarshal(data, decoded)
+	if err != nil {
+		return 0, err
+	}
+	if decoded.Type == nil {
+		return 0, fmt.Errorf("missing TLV type")
+	}
+	return *decoded.Type, nil
+}
+
+func marshalTLVJSON(tlvType Type, value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte{'{'}) {
+		return nil, fmt.Errorf("unexpected TLV encoding: %s", data)
+	}
+	tagged := fmt.Appendf(nil, `{"type":%d`, tlvType)
+	if !bytes.Equal(data, []byte("{}")) {
+		tagged = append(tagged, ',')
+	}
+	return append(tagged, data[1:]...), nil
+}
+
+func unmarshalTLVJSON(data []byte, tlvType Type, value any) error {
+	decodedType, err := peekTLVJSONType(data)
+	if err != nil {
+		return err
+	}
+	if decodedType != tlvType {
+		return fmt.Errorf("unexpected TLV type: %04xh (expected: %04xh)", decodedType, tlvType)
+	}
+	return json.Unmarshal(data, value)
+}
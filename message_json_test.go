@@ -0,0 +1,83 @@
+// message_json_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestMessageJSON(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"deviceAddress":"6c:b0:ce:1c:83:94"`)
+	require.Contains(t, string(data), `{"type":4,"mac":"6c:b0:ce:1c:83:94"}`)
+	require.Contains(t, string(data), `{"type":6,"ip":"10.1.0.3"}`)
+	decoded := &nsdp.Message{}
+	err = json.Unmarshal(data, decoded)
+	require.NoError(t, err)
+	require.Equal(t, msg.Marshal(), decoded.Marshal())
+	require.Equal(t, msg.String(), decoded.String())
+}
+
+func TestMessageJSONAllTLVs(t *testing.T) {
+	msg := nsdp.NewMessage(nsdp.WriteRequest)
+	msg.Header.DeviceAddress = getStaticMAC()
+	msg.Header.Unknown3 = 0x01020304
+	msg.AppendTLV(nsdp.NewDeviceModel("GS108Ev3"))
+	msg.AppendTLV(nsdp.NewDeviceName("switch1"))
+	msg.AppendTLV(nsdp.NewDeviceMAC(getStaticMAC()))
+	msg.AppendTLV(nsdp.NewDeviceLocation("Location"))
+	msg.AppendTLV(nsdp.NewDeviceIP(getStaticIP()))
+	msg.AppendTLV(nsdp.NewDeviceNetmask(getStaticIP()))
+	msg.AppendTLV(nsdp.NewRouterIP(getStaticIP()))
+	msg.AppendTLV(nsdp.NewDHCPMode(nsdp.DHCPRenew))
+	msg.AppendTLV(nsdp.NewFWVersionSlot1("1.2.3.4"))
+	msg.AppendTLV(nsdp.NewFWVersionSlot2("4.3.2.1"))
+	msg.AppendTLV(nsdp.NewNextFWSlot(2))
+	msg.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link2500MFull))
+	msg.AppendTLV(nsdp.NewPortStatistic(1, 2, 3, 4, 5, 6, 7))
+	msg.AppendTLV(nsdp.NewPortCount(8))
+	msg.AppendTLV(nsdp.NewPowerSaving(nsdp.PowerSavingEnabled))
+	msg.AppendTLV(nsdp.NewLEDControl(nsdp.LEDModeOff))
+	msg.AppendTLV(nsdp.NewRawTLV(0x7400, []byte{0x01, 0x02, 0x03}))
+	msg.AppendTLV(nsdp.EmptyDeviceIP())
+	msg.AppendTLV(nsdp.EmptyDeviceMAC())
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"type":29696,"data":"010203"}`)
+	decoded := &nsdp.Message{}
+	err = json.Unmarshal(data, decoded)
+	require.NoError(t, err)
+	require.Equal(t, msg.Body, decoded.Body)
+	require.Equal(t, msg.Marshal(), decoded.Marshal())
+}
+
+func TestMessageJSONInvalid(t *testing.T) {
+	const header = `{"version":1,"operation":2,"hostAddress":"00:00:00:00:00:00","deviceAddress":"00:00:00:00:00:00","signature":1314079824}`
+	decoded := &nsdp.Message{}
+	require.NoError(t, json.Unmarshal([]byte(`{"header":`+header+`,"body":[]}`), decoded))
+	_, err := nsdp.UnmarshalMessage(decoded.Marshal())
+	require.NoError(t, err)
+	require.Error(t, json.Unmarshal([]byte(`{"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":{},"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":{"hostAddress":"xx"},"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":`+strings.Replace(header, `"hostAddress":"00:00:00:00:00:00",`, "", 1)+`,"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":`+strings.Replace(header, `"version":1`, `"version":2`, 1)+`,"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":`+strings.Replace(header, `"operation":2`, `"operation":5`, 1)+`,"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":`+strings.Replace(header, `"signature":1314079824`, `"signature":0`, 1)+`,"body":[]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":`+header+`,"body":[{"name":"switch1"}]}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"header":`+header+`,"body":[{"type":29696,"data":"xx"}]}`), decoded))
+	deviceName := nsdp.EmptyDeviceName()
+	require.Error(t, json.Unmarshal([]byte(`{"type":1,"name":"switch1"}`), deviceName))
+}
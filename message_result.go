@@ -0,0 +1,119 @@
+// message_result.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Known operation results (the actual result code is encoded in the upper byte)
+const (
+	ResultSuccess         OperationResult = 0x0000
+	ResultInvalidValue    OperationResult = 0x0500 // The device rejected the reported TLV's value
+	ResultInvalidPassword OperationResult = 0x0700 // The device rejected the submitted password
+)
+
+// Errors matching ResultError instances (via errors.Is)
+var (
+	ErrOperationFailed = errors.New("operation failed")  // Matches any ResultError
+	ErrInvalidValue    = errors.New("invalid TLV value") // Matches ResultError instances for ResultInvalidValue
+	ErrInvalidPassword = errors.New("invalid password")  // Matches ResultError instances for ResultInvalidPassword
+)
+
+var resultErrors = map[OperationResult]error{
+	ResultInvalidValue:    ErrInvalidValue,
+	ResultInvalidPassword: ErrInvalidPassword,
+}
+
+// ResultError reports a non-zero operation result received from a device.
+type ResultError struct {
+	Device net.HardwareAddr // The hardware address of the reporting device
+	Result OperationResult  // The reported result
+	TLV    Type             // The offending TLV type (0 if not reported by the device)
+}
+
+func (err *ResultError) Error() string {
+	cause, found := resultErrors[err.Result]
+	if !found {
+		cause = ErrOperationFailed
+	}
+	if err.TLV != 0 {
+		return fmt.Sprintf("device %s: %v (result: %04xh, TLV type: %04xh)", err.Device, cause, uint16(err.Result), uint16(err.TLV))
+	}
+	return fmt.Sprintf("device %s: %v (result: %04xh)", err.Device, cause, uint16(err.Result))
+}
+
+// Is checks whether the error matches the given target error.
+//
+// Any ResultError matches ErrOperationFailed. Errors for a known result additionally match the corresponding
+// error (e.g. ErrInvalidPassword).
+func (err *ResultError) Is(target error) bool {
+	return target == ErrOperationFailed || target == resultErrors[err.Result]
+}
+
+// Err gets the error reported by the message's result.
+//
+// If the message's result is ResultSuccess, nil is returned. Otherwise a ResultError is returned.
+func (m *Message) Err() error {
+	if m.Header.Result == ResultSuccess {
+		return nil
+	}
+	return &ResultError{
+		Device: m.Header.DeviceAddress,
+		Result: m.Header.Result,
+		TLV:    m.Header.ErrorTLV(),
+	}
+}
+
+// DeviceErrors collects the errors reported by the individual devices.
+//
+// The map is build up using the device's hardware address string as the key (see Conn.SendReceiveMessage).
+type DeviceErrors map[string]error
+
+func (errs DeviceErrors) Error() string {
+	builder := &strings.Builder{}
+	for i, device := range slices.Sorted(maps.Keys(errs)) {
+		if i > 0 {
+			builder.WriteString("; ")
+		}
+		builder.WriteString(errs[device].Error())
+	}
+	return builder.String()
+}
+
+// Unwrap gets the collected errors.
+func (errs DeviceErrors) Unwrap() []error {
+	unwrapped := make([]error, 0, len(errs))
+	for _, device := range slices.Sorted(maps.Keys(errs)) {
+		unwrapped = append(unwrapped, errs[device])
+	}
+	return unwrapped
+}
+
+func checkMessageResults(msgs map[string]*Message) error {
+	var errs DeviceErrors
+	for device, msg := range msgs {
+		err := msg.Err()
+		if err == nil {
+			continue
+		}
+		if errs == nil {
+			errs = make(DeviceErrors)
+		}
+		errs[device] = err
+	}
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
@@ -81,6 +81,10 @@ func TestDHCPModeString(t *testing.T) {
 	runMessageStringTest(t, nsdp.NewDHCPMode(1), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: DHCPMode(000bh) Enabled\nEOM   : ffff0000h")
 }
 
+func TestDHCPModeRenewString(t *testing.T) {
+	runMessageStringTest(t, nsdp.NewDHCPMode(nsdp.DHCPRenew), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: DHCPMode(000bh) Renew\nEOM   : ffff0000h")
+}
+
 func TestFWVersionSlot1Marshaling(t *testing.T) {
 	runMessageMarshalingTest(t, nsdp.NewFWVersionSlot1("1.2.3.4"))
 }
@@ -105,6 +109,26 @@ func TestPortStatusString(t *testing.T) {
 	runMessageStringTest(t, nsdp.NewPortStatus(1, 2), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: PortStatus(0c00h) Port1 Status: 10Mbit/full-duplex Unknown1: 00h\nEOM   : ffff0000h")
 }
 
+func TestLinkStatus(t *testing.T) {
+	require.False(t, nsdp.LinkDown.Up())
+	require.Equal(t, uint(0), nsdp.LinkDown.Speed())
+	require.True(t, nsdp.Link100MHalf.Up())
+	require.Equal(t, uint(100), nsdp.Link100MHalf.Speed())
+	require.False(t, nsdp.Link100MHalf.FullDuplex())
+	require.Equal(t, uint(2500), nsdp.Link2500MFull.Speed())
+	require.True(t, nsdp.Link2500MFull.FullDuplex())
+	require.Equal(t, "10Gbit/full-duplex", nsdp.Link10GFull.String())
+	require.Equal(t, "ffh", nsdp.LinkStatus(0xff).String())
+}
+
+func TestPortStatusFlowControl(t *testing.T) {
+	msg, err := nsdp.UnmarshalMessage([]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4e, 0x53, 0x44, 0x50, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x03, 0x01, 0x08, 0x01, 0xff, 0xff, 0x00, 0x00})
+	require.NoError(t, err)
+	portStatus := msg.Body[0].(*nsdp.PortStatus)
+	require.Equal(t, nsdp.Link5GFull, portStatus.Status)
+	require.True(t, portStatus.FlowControl())
+}
+
 func TestPortStatisticMarshaling(t *testing.T) {
 	runMessageMarshalingTest(t, nsdp.NewPortStatistic(1, 2, 3, 4, 5, 6, 7))
 }
@@ -113,6 +137,83 @@ func TestPortStatisticString(t *testing.T) {
 	runMessageStringTest(t, nsdp.NewPortStatistic(1, 2, 3, 4, 5, 6, 7), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: PortStatistic(1000h) Port1 Received: 2, Sent: 3, Packets: 4, Broadcasts: 5, Multicasts: 6, Errors: 7\nEOM   : ffff0000h")
 }
 
+func TestPortCountMarshaling(t *testing.T) {
+	runMessageMarshalingTest(t, nsdp.NewPortCount(8))
+}
+
+func TestPortCountString(t *testing.T) {
+	runMessageStringTest(t, nsdp.NewPortCount(8), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: PortCount(6000h) 8\nEOM   : ffff0000h")
+}
+
+func TestPowerSavingMarshaling(t *testing.T) {
+	runMessageMarshalingTest(t, nsdp.NewPowerSaving(nsdp.PowerSavingEnabled))
+}
+
+func TestPowerSavingString(t *testing.T) {
+	runMessageStringTest(t, nsdp.NewPowerSaving(nsdp.PowerSavingEnabled), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: PowerSaving(9400h) Enabled\nEOM   : ffff0000h")
+}
+
+func TestLEDControlMarshaling(t *testing.T) {
+	runMessageMarshalingTest(t, nsdp.NewLEDControl(nsdp.LEDModeOff))
+}
+
+func TestLEDControlString(t *testing.T) {
+	runMessageStringTest(t, nsdp.NewLEDControl(nsdp.LEDModeOff), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: LEDControl(9800h) Off\nEOM   : ffff0000h")
+}
+
+func TestRawTLVMarshaling(t *testing.T) {
+	runMessageMarshalingTest(t, nsdp.NewRawTLV(0x7400, []byte{0x01, 0x02, 0x03}))
+}
+
+func TestRawTLVString(t *testing.T) {
+	runMessageStringTest(t, nsdp.NewRawTLV(0x7400, []byte{0x01, 0x02, 0x03}), "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\nTLV[0]: RawTLV(7400h) 010203\nEOM   : ffff0000h")
+}
+
+func TestUnmarshalMessageStrict(t *testing.T) {
+	message := nsdp.NewMessage(nsdp.ReadResponse)
+	message.AppendTLV(nsdp.NewDeviceName("Name"))
+	message.AppendTLV(nsdp.NewRawTLV(0x7400, []byte{0x01, 0x02, 0x03}))
+	marshaledBytes := message.Marshal()
+	_, err := nsdp.UnmarshalMessageStrict(marshaledBytes)
+	require.Error(t, err)
+	unmarshaled, err := nsdp.UnmarshalMessage(marshaledBytes)
+	require.NoError(t, err)
+	require.IsType(t, &nsdp.DeviceName{}, unmarshaled.Body[0])
+	require.IsType(t, &nsdp.RawTLV{}, unmarshaled.Body[1])
+	require.Equal(t, marshaledBytes, unmarshaled.Marshal())
+}
+
+func TestUnmarshalMessageMalformed(t *testing.T) {
+	message := nsdp.NewMessage(nsdp.ReadResponse)
+	message.AppendTLV(nsdp.NewDeviceName("Name"))
+	message.AppendTLV(nsdp.NewPortStatistic(1, 2, 3, 4, 5, 6, 7))
+	marshaledBytes := message.Marshal()
+	for length := range len(marshaledBytes) {
+		_, err := nsdp.UnmarshalMessage(marshaledBytes[:length])
+		require.Error(t, err, "length: %d", length)
+	}
+	excessiveLength := append([]byte{}, marshaledBytes...)
+	excessiveLength[0x22] = 0xff
+	_, err := nsdp.UnmarshalMessage(excessiveLength)
+	require.Error(t, err)
+	invalidLength := append([]byte{}, marshaledBytes...)
+	invalidLength[0x2b] = 0x30
+	_, err = nsdp.UnmarshalMessage(invalidLength)
+	require.Error(t, err)
+	_, err = nsdp.UnmarshalMessage(append(marshaledBytes, make([]byte, 0x10000)...))
+	require.Error(t, err)
+}
+
+func TestUnmarshalReadRequestValues(t *testing.T) {
+	message := nsdp.NewMessage(nsdp.ReadResponse)
+	message.AppendTLV(nsdp.NewDeviceName("Name"))
+	marshaledBytes := message.Marshal()
+	marshaledBytes[1] = byte(nsdp.ReadRequest)
+	unmarshaled, err := nsdp.UnmarshalMessage(marshaledBytes)
+	require.NoError(t, err)
+	require.Equal(t, nsdp.EmptyDeviceName(), unmarshaled.Body[0])
+}
+
 func runMessageMarshalingTest(t *testing.T, tlv nsdp.TLV) {
 	runRequestMessageMarshalingTest(t, tlv)
 	runResponseMessageMarshalingTest(t, tlv)
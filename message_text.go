@@ -0,0 +1,451 @@
+// message_text.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseError reports a syntax error encountered while parsing a message's textual representation.
+type ParseError struct {
+	Line   int   // Line number (starting at 1)
+	Column int   // Column number (starting at 1)
+	Err    error // The actual error
+}
+
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", err.Line, err.Column, err.Err)
+}
+
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// MarshalText encodes the message to the textual representation understood by ParseMessage.
+//
+// The representation matches the one generated by Message.String, except for registered TLVs without a textual
+// representation known to ParseMessage. These are represented by their raw data (see RawTLV).
+func (m *Message) MarshalText() ([]byte, error) {
+	builder := &strings.Builder{}
+	m.writeString(builder, textTLV)
+	return []byte(builder.String()), nil
+}
+
+// UnmarshalText decodes the textual representation of a message (see ParseMessage).
+func (m *Message) UnmarshalText(text []byte) error {
+	parsed, err := ParseMessage(string(text))
+	if err != nil {
+		return err
+	}
+	*m = *parsed
+	return nil
+}
+
+// ParseMessage parses the textual representation of a message as generated by Message.MarshalText (or Message.String
+// for messages without registered TLVs).
+//
+// All built-in TLVs as well as RawTLV elements are supported. As the textual representation omits the header's
+// Unknown3 field, it is always set to 0. Errors are reported as ParseError instances pointing at the offending
+// line and column.
+//
+// RawTLV elements of a registered type are decoded using the registered decoder (see RegisterTLV). Registered TLVs
+// implementing encoding.TextUnmarshaler receive the text following their type code (their String method has to
+// generate the matching 'Name(<type>h) <value>' format). Message.MarshalText represents any other registered TLV by
+// its raw data, hence such TLVs are restored via their registered decoder as well.
+func ParseMessage(text string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) < 2 {
+		return nil, &ParseError{Line: len(lines) + 1, Column: 1, Err: errors.New("incomplete message")}
+	}
+	header, err := parseHeaderText(1, lines[0])
+	if err != nil {
+		return nil, err
+	}
+	tlvs := make([]TLV, 0, len(lines)-2)
+	for i, line := range lines[1 : len(lines)-1] {
+		tlv, err := parseTLVText(i+2, i, line)
+		if err != nil {
+			return nil, err
+		}
+		tlvs = append(tlvs, tlv)
+	}
+	eom, err := parseEOMText(len(lines), lines[len(lines)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		Header: header,
+		Body:   tlvs,
+		EOM:    eom,
+	}, nil
+}
+
+const headerTextPrefix = "Header: "
+
+func parseHeaderText(lineNo int, line string) (*Header, error) {
+	if !strings.HasPrefix(line, headerTextPrefix) {
+		return nil, &ParseError{Line: lineNo, Column: 1, Err: fmt.Errorf("expected '%s'", strings.TrimSpace(headerTextPrefix))}
+	}
+	fields := newTextFields(lineNo, line, len(headerTextPrefix))
+	header := &Header{}
+	version, err := fields.hex(8)
+	if err != nil {
+		return nil, err
+	}
+	if version != uint64(ProtoVersion1) {
+		return nil, fields.errorf("unrecognized proto version: %02xh", version)
+	}
+	header.Version = ProtoVersion(version)
+	operation, err := fields.hex(8)
+	if err != nil {
+		return nil, err
+	}
+	if operation != uint64(ReadRequest) && operation != uint64(ReadResponse) && operation != uint64(WriteRequest) && operation != uint64(WriteResponse) {
+		return nil, fields.errorf("unrecognized operation code: %02xh", operation)
+	}
+	header.Operation = OperationCode(operation)
+	result, err := fields.hex(16)
+	if err != nil {
+		return nil, err
+	}
+	header.Result = OperationResult(result)
+	unknown1, err := fields.hex(32)
+	if err != nil {
+		return nil, err
+	}
+	header.Unknown1 = uint32(unknown1)
+	header.HostAddress, err = fields.mac()
+	if err != nil {
+		return nil, err
+	}
+	header.DeviceAddress, err = fields.mac()
+	if err != nil {
+		return nil, err
+	}
+	unknown2, err := fields.hex(16)
+	if err != nil {
+		return nil, err
+	}
+	header.Unknown2 = uint16(unknown2)
+	sequence, err := fields.hex(16)
+	if err != nil {
+		return nil, err
+	}
+	header.Sequence = Sequence(sequence)
+	signature, err := fields.hex(32)
+	if err != nil {
+		return nil, err
+	}
+	if signature != uint64(NSDPSignature) {
+		return nil, fields.errorf("unrecognized signature: %08xh", signature)
+	}
+	header.Signature = Signature(signature)
+	return header, fields.end()
+}
+
+var tlvTextPattern = regexp.MustCompile(`^TLV\[(\d+)\]: (\w+)\(([0-9a-f]{4})h\) (.*)$`)
+
+func parseTLVText(lineNo int, index int, line string) (TLV, error) {
+	match := tlvTextPattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, &ParseError{Line: lineNo, Column: 1, Err: errors.New("expected 'TLV[<index>]: <name>(<type>h) <value>'")}
+	}
+	parsedIndex, err := strconv.Atoi(line[match[2]:match[3]])
+	if err != nil || parsedIndex != index {
+		return nil, &ParseError{Line: lineNo, Column: match[2] + 1, Err: fmt.Errorf("unexpected TLV index: %s (expected: %d)", line[match[2]:match[3]], index)}
+	}
+	name := line[match[4]:match[5]]
+	tlvType, _ := strconv.ParseUint(line[match[6]:match[7]], 16, 16)
+	value := line[match[8]:match[9]]
+	var tlv TLV
+	if name == rawTLVTextName {
+		tlv, err = parseRawTLVText(Type(tlvType), value)
+	} else if parser, found := tlvTextParsers[Type(tlvType)]; found {
+		if parser.name != name {
+			return nil, &ParseError{Line: lineNo, Column: match[4] + 1, Err: fmt.Errorf("unexpected TLV name: %s (expected: %s)", name, parser.name)}
+		}
+		tlv, err = parser.parse(value)
+	} else {
+		tlv = newEmptyTLV(Type(tlvType))
+		unmarshaler, ok := tlv.(encoding.TextUnmarshaler)
+		if _, isRaw := tlv.(*RawTLV); !ok || isRaw {
+			return nil, &ParseError{Line: lineNo, Column: match[6] + 1, Err: fmt.Errorf("unsupported TLV type: %04xh", tlvType)}
+		}
+		err = unmarshaler.UnmarshalText([]byte(value))
+	}
+	if err != nil {
+		return nil, &ParseError{Line: lineNo, Column: match[8] + 1, Err: err}
+	}
+	return tlv, nil
+}
+
+const eomTextPrefix = "EOM   : "
+
+func parseEOMText(lineNo int, line string) (*EOM, error) {
+	if !strings.HasPrefix(line, eomTextPrefix) {
+		return nil, &ParseError{Line: lineNo, Column: 1, Err: fmt.Errorf("expected '%s'", strings.TrimSpace(eomTextPrefix))}
+	}
+	fields := newTextFields(lineNo, line, len(eomTextPrefix))
+	marker, err := fields.hex(32)
+	if err != nil {
+		return nil, err
+	}
+	if marker != uint64(EOMMarker) {
+		return nil, fields.errorf("unexpected EOM marker: %08xh", marker)
+	}
+	return &EOM{Marker: uint32(marker)}, fields.end()
+}
+
+type textFields struct {
+	lineNo int
+	line   string
+	pos    int
+	start  int
+}
+
+func newTextFields(lineNo int, line string, pos int) *textFields {
+	return &textFields{lineNo: lineNo, line: line, pos: pos, start: pos}
+}
+
+func (fields *textFields) next() (string, error) {
+	if fields.pos > len(fields.line) {
+		fields.start = len(fields.line)
+		return "", fields.errorf("missing field")
+	}
+	fields.start = fields.pos
+	end := strings.IndexByte(fields.line[fields.pos:], ' ')
+	if end < 0 {
+		end = len(fields.line) - fields.pos
+	}
+	field := fields.line[fields.pos : fields.pos+end]
+	fields.pos += end + 1
+	if field == "" {
+		return "", fields.errorf("missing field")
+	}
+	return field, nil
+}
+
+func (fields *textFields) hex(bits int) (uint64, error) {
+	field, err := fields.next()
+	if err != nil {
+		return 0, err
+	}
+	digits, found := strings.CutSuffix(field, "h")
+	if !found || len(digits) != bits/4 {
+		return 0, fields.errorf("expected %d digit hex value: %s", bits/4, field)
+	}
+	value, err := strconv.ParseUint(digits, 16, bits)
+	if err != nil {
+		return 0, fields.errorf("invalid hex value: %s", field)
+	}
+	return value, nil
+}
+
+func (fields *textFields) mac() (net.HardwareAddr, error) {
+	field, err := fields.next()
+	if err != nil {
+		return nil, err
+	}
+	mac, err := net.ParseMAC(field)
+	if err != nil || len(mac) != 6 {
+		return nil, fields.errorf("invalid MAC: %s", field)
+	}
+	return mac, nil
+}
+
+func (fields *textFields) end() error {
+	if fields.pos < len(fields.line) {
+		fields.start = fields.pos
+		return fields.errorf("unexpected trailing text: %s", fields.line[fields.pos:])
+	}
+	return nil
+}
+
+func (fields *textFields) errorf(format string, args ...any) error {
+	return &ParseError{Line: fields.lineNo, Column: fields.start + 1, Err: fmt.Errorf(format, args...)}
+}
+
+type tlvTextParser struct {
+	name  string
+	parse func(text string) (TLV, error)
+}
+
+var tlvTextParsers = map[Type]tlvTextParser{
+	TypeDeviceModel:    {name: "DeviceModel", parse: stringTextParser(NewDeviceModel)},
+	TypeDeviceName:     {name: "DeviceName", parse: stringTextParser(NewDeviceName)},
+	TypeDeviceMAC:      {name: "DeviceMAC", parse: parseDeviceMACText},
+	TypeDeviceLocation: {name: "DeviceLocation", parse: stringTextParser(NewDeviceLocation)},
+	TypeDeviceIP:       {name: "DeviceIP", parse: ipTextParser(NewDeviceIP)},
+	TypeDeviceNetmask:  {name: "DeviceNetmask", parse: ipTextParser(NewDeviceNetmask)},
+	TypeRouterIP:       {name: "RouterIP", parse: ipTextParser(NewRouterIP)},
+	TypeDHCPMode:       {name: "DHCPMode", parse: enumTextParser(NewDHCPMode)},
+	TypeFWVersionSlot1: {name: "FWVersionSlot1", parse: stringTextParser(NewFWVersionSlot1)},
+	TypeFWVersionSlot2: {name: "FWVersionSlot2", parse: stringTextParser(NewFWVersionSlot2)},
+	TypeNextFWSlot:     {name: "NextFWSlot", parse: uint8TextParser(NewNextFWSlot)},
+	TypePortStatus:     {name: "PortStatus", parse: parsePortStatusText},
+	TypePortStatistic:  {name: "PortStatistic", parse: parsePortStatisticText},
+	TypePortCount:      {name: "PortCount", parse: uint8TextParser(NewPortCount)},
+	TypePowerSaving:    {name: "PowerSaving", parse: enumTextParser(NewPowerSaving)},
+	TypeLEDControl:     {name: "LEDControl", parse: enumTextParser(NewLEDControl)},
+}
+
+func stringTextParser[T TLV](newTLV func(string) T) func(string) (TLV, error) {
+	return func(text string) (TLV, error) {
+		if len(text) < 2 || text[0] != '\'' || text[len(text)-1] != '\'' {
+			return nil, fmt.Errorf("expected quoted string: %s", text)
+		}
+		return newTLV(text[1 : len(text)-1]), nil
+	}
+}
+
+func ipTextParser[T TLV](newTLV func(net.IP) T) func(string) (TLV, error) {
+	return func(text string) (TLV, error) {
+		if text == "<nil>" {
+			return newTLV(net.IP{}), nil
+		}
+		ip := net.ParseIP(text)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP: %s", text)
+		}
+		ip4 := ip.To4()
+		if ip4 != nil {
+			return newTLV(ip4), nil
+		}
+		return newTLV(ip), nil
+	}
+}
+
+func uint8TextParser[T TLV](newTLV func(uint8) T) func(string) (TLV, error) {
+	return func(text string) (TLV, error) {
+		value, err := strconv.ParseUint(text, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", text)
+		}
+		return newTLV(uint8(value)), nil
+	}
+}
+
+type textEnum interface {
+	~uint8
+	String() string
+}
+
+func enumTextParser[E textEnum, T TLV](newTLV func(E) T) func(string) (TLV, error) {
+	return func(text string) (TLV, error) {
+		value, err := parseEnumText[E](text)
+		if err != nil {
+			return nil, err
+		}
+		return newTLV(value), nil
+	}
+}
+
+func parseEnumText[E textEnum](text string) (E, error) {
+	for i := range 256 {
+		value := E(i)
+		if value.String() == text {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized value: %s", text)
+}
+
+func parseDeviceMACText(text string) (TLV, error) {
+	if text == "" {
+		return EmptyDeviceMAC(), nil
+	}
+	mac, err := net.ParseMAC(text)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("invalid MAC: %s", text)
+	}
+	return NewDeviceMAC(mac), nil
+}
+
+var portStatusTextPattern = regexp.MustCompile(`^Port(\d+) Status: (.+) Unknown1: ([0-9a-f]{2})h$`)
+
+func parsePortStatusText(text string) (TLV, error) {
+	match := portStatusTextPattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("expected 'Port<port> Status: <status> Unknown1: <flags>h': %s", text)
+	}
+	port, err := strconv.ParseUint(match[1], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %s", match[1])
+	}
+	status, err := parseEnumText[LinkStatus](match[2])
+	if err != nil {
+		return nil, err
+	}
+	unknown1, _ := strconv.ParseUint(match[3], 16, 8)
+	tlv := NewPortStatus(uint8(port), status)
+	tlv.Unknown1 = uint8(unknown1)
+	return tlv, nil
+}
+
+var portStatisticTextPattern = regexp.MustCompile(`^Port(\d+) Received: (\d+), Sent: (\d+), Packets: (\d+), Broadcasts: (\d+), Multicasts: (\d+), Errors: (\d+)$`)
+
+func parsePortStatisticText(text string) (TLV, error) {
+	match := portStatisticTextPattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("expected 'Port<port> Received: <n>, Sent: <n>, Packets: <n>, Broadcasts: <n>, Multicasts: <n>, Errors: <n>': %s", text)
+	}
+	port, err := strconv.ParseUint(match[1], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %s", match[1])
+	}
+	counters := make([]uint64, 6)
+	for i := range counters {
+		counters[i], err = strconv.ParseUint(match[i+2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter: %s", match[i+2])
+		}
+	}
+	return NewPortStatistic(uint8(port), counters[0], counters[1], counters[2], counters[3], counters[4], counters[5]), nil
+}
+
+const rawTLVTextName = "RawTLV"
+
+func parseRawTLVText(tlvType Type, text string) (TLV, error) {
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw TLV data: %s", text)
+	}
+	decodedTLV, err := unmarshalTLV(uint16(tlvType), data)
+	if err != nil {
+		return NewRawTLV(tlvType, data), nil
+	}
+	return decodedTLV, nil
+}
+
+// textTLV gets the TLV to use for the given TLV's textual representation (see Message.MarshalText). TLVs without a textual representation
+// known to ParseMessage are represented by their raw data (see RawTLV).
+func textTLV(tlv TLV) TLV {
+	_, found := tlvTextParsers[tlv.Type()]
+	if found {
+		return tlv
+	}
+	_, ok := tlv.(encoding.TextUnmarshaler)
+	if ok {
+		return tlv
+	}
+	_, isRaw := tlv.(*RawTLV)
+	if isRaw {
+		return tlv
+	}
+	return NewRawTLV(tlv.Type(), tlv.Value())
+}
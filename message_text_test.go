@@ -0,0 +1,146 @@
+// message_text_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+func TestParseMessage(t *testing.T) {
+	msg := unmarshalTestMessage(t, connTestResponseSwitch1)
+	parsed, err := nsdp.ParseMessage(msg.String())
+	require.NoError(t, err)
+	require.Equal(t, msg.Marshal(), parsed.Marshal())
+	require.Equal(t, msg.String(), parsed.String())
+}
+
+func TestParseMessageAllTLVs(t *testing.T) {
+	msg := nsdp.NewMessage(nsdp.WriteRequest)
+	msg.Header.DeviceAddress = getStaticMAC()
+	msg.Header.Unknown1 = 0x00070000
+	msg.Header.Sequence = 0x1234
+	msg.AppendTLV(nsdp.NewDeviceModel("GS108Ev3"))
+	msg.AppendTLV(nsdp.NewDeviceName("switch 'one'"))
+	msg.AppendTLV(nsdp.NewDeviceMAC(getStaticMAC()))
+	msg.AppendTLV(nsdp.NewDeviceLocation(""))
+	msg.AppendTLV(nsdp.NewDeviceIP(getStaticIP()))
+	msg.AppendTLV(nsdp.NewDeviceNetmask(getStaticIP()))
+	msg.AppendTLV(nsdp.NewRouterIP(getStaticIP()))
+	msg.AppendTLV(nsdp.NewRawTLV(nsdp.TypePassword, []byte("password")))
+	msg.AppendTLV(nsdp.NewDHCPMode(nsdp.DHCPRenew))
+	msg.AppendTLV(nsdp.NewDHCPMode(0x03))
+	msg.AppendTLV(nsdp.NewFWVersionSlot1("1.2.3.4"))
+	msg.AppendTLV(nsdp.NewFWVersionSlot2("4.3.2.1"))
+	msg.AppendTLV(nsdp.NewNextFWSlot(2))
+	msg.AppendTLV(nsdp.NewPortStatus(1, nsdp.Link2500MFull))
+	msg.AppendTLV(nsdp.NewPortStatus(2, 0x42))
+	msg.AppendTLV(nsdp.NewPortStatistic(1, 2, 3, 4, 5, 6, 7))
+	msg.AppendTLV(nsdp.NewPortCount(8))
+	msg.AppendTLV(nsdp.NewPowerSaving(nsdp.PowerSavingEnabled))
+	msg.AppendTLV(nsdp.NewLEDControl(nsdp.LEDModeOff))
+	msg.AppendTLV(nsdp.NewRawTLV(0x7400, []byte{0x01, 0x02, 0x03}))
+	msg.AppendTLV(nsdp.EmptyDeviceIP())
+	msg.AppendTLV(nsdp.EmptyDeviceMAC())
+	parsed, err := nsdp.ParseMessage(msg.String())
+	require.NoError(t, err)
+	require.Equal(t, msg.Body, parsed.Body)
+	require.Equal(t, msg.Marshal(), parsed.Marshal())
+	require.Equal(t, msg.String(), parsed.String())
+}
+
+func TestParseMessageRegisteredTLV(t *testing.T) {
+	const rawType nsdp.Type = 0x7401
+	const textType nsdp.Type = 0x7402
+	nsdp.RegisterTLV(rawType, func(value []byte) (nsdp.TLV, error) {
+		return &testRawTextTLV{value: string(value)}, nil
+	})
+	defer nsdp.UnregisterTLV(rawType)
+	nsdp.RegisterTLV(textType, func(value []byte) (nsdp.TLV, error) {
+		return &testTextTLV{testRawTextTLV{value: string(value)}}, nil
+	})
+	defer nsdp.UnregisterTLV(textType)
+	msg := nsdp.NewMessage(nsdp.ReadResponse)
+	msg.AppendTLV(&testRawTextTLV{value: "raw"})
+	msg.AppendTLV(&testTextTLV{testRawTextTLV{value: "text"}})
+	require.Contains(t, msg.String(), "TLV[0]: TestRaw(7401h) raw\n")
+	text, err := msg.MarshalText()
+	require.NoError(t, err)
+	require.Contains(t, string(text), "TLV[0]: RawTLV(7401h) 726177\n")
+	require.Contains(t, string(text), "TLV[1]: TestText(7402h) text\n")
+	parsed := &nsdp.Message{}
+	err = parsed.UnmarshalText(text)
+	require.NoError(t, err)
+	require.Equal(t, msg.Body, parsed.Body)
+	require.Equal(t, msg.Marshal(), parsed.Marshal())
+}
+
+type testRawTextTLV struct {
+	value string
+}
+
+func (tlv *testRawTextTLV) Type() nsdp.Type {
+	return 0x7401
+}
+
+func (tlv *testRawTextTLV) Length() uint16 {
+	return uint16(len(tlv.value))
+}
+
+func (tlv *testRawTextTLV) Value() []byte {
+	return []byte(tlv.value)
+}
+
+func (tlv *testRawTextTLV) String() string {
+	return fmt.Sprintf("TestRaw(%04xh) %s", tlv.Type(), tlv.value)
+}
+
+type testTextTLV struct {
+	testRawTextTLV
+}
+
+func (tlv *testTextTLV) Type() nsdp.Type {
+	return 0x7402
+}
+
+func (tlv *testTextTLV) String() string {
+	return fmt.Sprintf("TestText(%04xh) %s", tlv.Type(), tlv.value)
+}
+
+func (tlv *testTextTLV) UnmarshalText(text []byte) error {
+	tlv.value = string(text)
+	return nil
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	const header = "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h"
+	const eom = "EOM   : ffff0000h"
+	runParseErrorTest(t, header, 2, 1)
+	runParseErrorTest(t, "Header: 02h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h 4e534450h\n"+eom, 1, 9)
+	runParseErrorTest(t, "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:zz 0000h 0000h 4e534450h\n"+eom, 1, 51)
+	runParseErrorTest(t, "Header: 01h 02h 0000h 00000000h 00:00:00:00:00:00 00:00:00:00:00:00 0000h 0000h\n"+eom, 1, 80)
+	runParseErrorTest(t, header+" x\n"+eom, 1, 91)
+	runParseErrorTest(t, header+"\nTLV[1]: DeviceName(0003h) 'Name'\n"+eom, 2, 5)
+	runParseErrorTest(t, header+"\nTLV[0]: DeviceModel(0003h) 'Name'\n"+eom, 2, 9)
+	runParseErrorTest(t, header+"\nTLV[0]: DeviceName(0003h) Name\n"+eom, 2, 27)
+	runParseErrorTest(t, header+"\nTLV[0]: Unknown(7401h) 00\n"+eom, 2, 17)
+	runParseErrorTest(t, header+"\nTLV[0]: DeviceName(0003h) 'Name'\nTLV[1]: PortStatus(0c00h) Port1 Status: Up Unknown1: 00h\n"+eom, 3, 27)
+	runParseErrorTest(t, header+"\nEOM   : ffff0001h", 2, 9)
+}
+
+func runParseErrorTest(t *testing.T, text string, line int, column int) {
+	_, err := nsdp.ParseMessage(text)
+	var parseErr *nsdp.ParseError
+	require.True(t, errors.As(err, &parseErr), "unexpected error: %v", err)
+	require.Equal(t, line, parseErr.Line, parseErr.Error())
+	require.Equal(t, column, parseErr.Column, parseErr.Error())
+}
@@ -15,7 +15,7 @@ import (
 //
 // Add an empty DeviceModel TLV to a read request to get a filled one back.
 type DeviceModel struct {
-	Model string // Model name (e.g. GS108Ev3)
+	Model string `json:"model"` // Model name (e.g. GS108Ev3)
 }
 
 func EmptyDeviceModel() *DeviceModel {
@@ -42,6 +42,39 @@ func (tlv *DeviceModel) Value() []byte {
 	return []byte(tlv.Model)
 }
 
+func (tlv *DeviceModel) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Model...)
+}
+
+func (tlv *DeviceModel) unmarshalValue(value []byte) error {
+	if tlv.Model != string(value) {
+		tlv.Model = string(value)
+	}
+	return nil
+}
+
 func (tlv *DeviceModel) String() string {
 	return fmt.Sprintf("DeviceModel(%04xh) '%s'", TypeDeviceModel, tlv.Model)
 }
+
+func (tlv *DeviceModel) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DeviceModel) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DeviceModel) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDeviceModel, tlv)
+}
+
+type deviceModelJSON DeviceModel
+
+func (tlv *DeviceModel) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDeviceModel, (*deviceModelJSON)(tlv))
+}
+
+func (tlv *DeviceModel) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeDeviceModel, (*deviceModelJSON)(tlv))
+}
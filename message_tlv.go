@@ -7,7 +7,10 @@
 
 package nsdp
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type Type uint16
 
@@ -29,6 +32,9 @@ const (
 	TypePortStatistic  Type = 0x1000
 	TypeGetVlanInfo    Type = 0x2800
 	TypeDeleteVlan     Type = 0x2c00
+	TypePortCount      Type = 0x6000
+	TypePowerSaving    Type = 0x9400 // not yet backed by a captured fixture
+	TypeLEDControl     Type = 0x9800 // not yet backed by a captured fixture
 	TypeEOM            Type = 0xffff // EOM marker prefix (always the last TLV and automatically part of each message)
 )
 
@@ -39,34 +45,52 @@ type TLV interface {
 	Value() []byte
 }
 
+// Interface for TLVs, which are reported once per port (e.g. PortStatus).
+type PortTLV interface {
+	TLV
+	PortNumber() uint8
+}
+
+// tlvValidator is implemented by TLVs which restrict the values accepted for a specific operation.
+type tlvValidator interface {
+	validate(operation OperationCode) error
+}
+
+// tlvValueAppender is implemented by TLVs which encode their value without allocating an intermediate buffer.
+type tlvValueAppender interface {
+	appendValue(buf []byte) []byte
+}
+
+// tlvValueUnmarshaler is implemented by TLVs which decode their value in place (see Decoder).
+type tlvValueUnmarshaler interface {
+	unmarshalValue(value []byte) error
+}
+
+func appendTLVValue(buf []byte, tlv TLV) []byte {
+	appender, ok := tlv.(tlvValueAppender)
+	if ok {
+		return appender.appendValue(buf)
+	}
+	return append(buf, tlv.Value()...)
+}
+
 func unmarshalTLV(tlvType uint16, tlvValue []byte) (TLV, error) {
-	switch tlvType {
-	case uint16(TypeDeviceModel):
-		return unmarshalDeviceModel(tlvValue)
-	case uint16(TypeDeviceName):
-		return unmarshalDeviceName(tlvValue)
-	case uint16(TypeDeviceMAC):
-		return unmarshalDeviceMAC(tlvValue)
-	case uint16(TypeDeviceLocation):
-		return unmarshalDeviceLocation(tlvValue)
-	case uint16(TypeDeviceIP):
-		return unmarshalDeviceIP(tlvValue)
-	case uint16(TypeDeviceNetmask):
-		return unmarshalDeviceNetmask(tlvValue)
-	case uint16(TypeRouterIP):
-		return unmarshalRouterIP(tlvValue)
-	case uint16(TypeDHCPMode):
-		return unmarshalDHCPMode(tlvValue)
-	case uint16(TypeFWVersionSlot1):
-		return unmarshalFWVersionSlot1(tlvValue)
-	case uint16(TypeFWVersionSlot2):
-		return unmarshalFWVersionSlot2(tlvValue)
-	case uint16(TypeNextFWSlot):
-		return unmarshalNextFWSlot(tlvValue)
-	case uint16(TypePortStatus):
-		return unmarshalPortStatus(tlvValue)
-	case uint16(TypePortStatistic):
-		return unmarshalPortStatistic(tlvValue)
+	decoder, found := LookupTLVDecoder(Type(tlvType))
+	if !found {
+		return nil, fmt.Errorf("%w: %04xh", errUnrecognizedTLVType, tlvType)
+	}
+	return decoder(tlvValue)
+}
+
+var errUnrecognizedTLVType = errors.New("unrecognized TLV type")
+
+func newEmptyTLV(tlvType Type) TLV {
+	decoder, found := LookupTLVDecoder(tlvType)
+	if found {
+		tlv, err := decoder(nil)
+		if err == nil {
+			return tlv
+		}
 	}
-	return nil, fmt.Errorf("unrecognized TLV type: %04xh", tlvType)
+	return NewRawTLV(tlvType, nil)
 }
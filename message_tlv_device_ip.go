@@ -28,14 +28,12 @@ func NewDeviceIP(ip net.IP) *DeviceIP {
 }
 
 func unmarshalDeviceIP(bytes []byte) (*DeviceIP, error) {
-	len := len(bytes)
-	if len == 0 {
-		return EmptyDeviceIP(), nil
+	tlv := EmptyDeviceIP()
+	err := tlv.unmarshalValue(bytes)
+	if err != nil {
+		return nil, err
 	}
-	if len != 4 && len != 16 {
-		return nil, fmt.Errorf("unexpected device IP length: %d", len)
-	}
-	return NewDeviceIP(net.IP(bytes)), nil
+	return tlv, nil
 }
 
 func (tlv *DeviceIP) Type() Type {
@@ -50,6 +48,53 @@ func (tlv *DeviceIP) Value() []byte {
 	return tlv.IP
 }
 
+func (tlv *DeviceIP) appendValue(buf []byte) []byte {
+	return append(buf, tlv.IP...)
+}
+
+func (tlv *DeviceIP) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len != 0 && len != 4 && len != 16 {
+		return fmt.Errorf("unexpected device IP length: %d", len)
+	}
+	tlv.IP = append(tlv.IP[:0], value...)
+	return nil
+}
+
 func (tlv *DeviceIP) String() string {
 	return fmt.Sprintf("DeviceIP(%04xh) %s", TypeDeviceIP, tlv.IP)
 }
+
+func (tlv *DeviceIP) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DeviceIP) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DeviceIP) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDeviceIP, tlv)
+}
+
+type deviceIPJSON struct {
+	IP string `json:"ip"`
+}
+
+func (tlv *DeviceIP) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDeviceIP, &deviceIPJSON{IP: formatJSONIP(tlv.IP)})
+}
+
+func (tlv *DeviceIP) UnmarshalJSON(data []byte) error {
+	decoded := &deviceIPJSON{}
+	err := unmarshalTLVJSON(data, TypeDeviceIP, decoded)
+	if err != nil {
+		return err
+	}
+	ip, err := parseJSONIP(decoded.IP)
+	if err != nil {
+		return err
+	}
+	tlv.IP = ip
+	return nil
+}
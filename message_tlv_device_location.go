@@ -15,7 +15,7 @@ import (
 //
 // Add an empty DeviceLocation TLV to a read request to get a filled one back.
 type DeviceLocation struct {
-	Location string // Device location text
+	Location string `json:"location"` // Device location text
 }
 
 func EmptyDeviceLocation() *DeviceLocation {
@@ -42,6 +42,39 @@ func (tlv *DeviceLocation) Value() []byte {
 	return []byte(tlv.Location)
 }
 
+func (tlv *DeviceLocation) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Location...)
+}
+
+func (tlv *DeviceLocation) unmarshalValue(value []byte) error {
+	if tlv.Location != string(value) {
+		tlv.Location = string(value)
+	}
+	return nil
+}
+
 func (tlv *DeviceLocation) String() string {
 	return fmt.Sprintf("DeviceLocation(%04xh) '%s'", TypeDeviceLocation, tlv.Location)
 }
+
+func (tlv *DeviceLocation) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DeviceLocation) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DeviceLocation) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDeviceLocation, tlv)
+}
+
+type deviceLocationJSON DeviceLocation
+
+func (tlv *DeviceLocation) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDeviceLocation, (*deviceLocationJSON)(tlv))
+}
+
+func (tlv *DeviceLocation) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeDeviceLocation, (*deviceLocationJSON)(tlv))
+}
@@ -28,14 +28,12 @@ func NewDeviceMAC(mac net.HardwareAddr) *DeviceMAC {
 }
 
 func unmarshalDeviceMAC(bytes []byte) (*DeviceMAC, error) {
-	len := len(bytes)
-	if len == 0 {
-		return EmptyDeviceMAC(), nil
+	tlv := EmptyDeviceMAC()
+	err := tlv.unmarshalValue(bytes)
+	if err != nil {
+		return nil, err
 	}
-	if len != 6 {
-		return nil, fmt.Errorf("unexpected device MAC length: %d", len)
-	}
-	return NewDeviceMAC(net.HardwareAddr(bytes)), nil
+	return tlv, nil
 }
 
 func (tlv *DeviceMAC) Type() Type {
@@ -50,6 +48,53 @@ func (tlv *DeviceMAC) Value() []byte {
 	return tlv.MAC
 }
 
+func (tlv *DeviceMAC) appendValue(buf []byte) []byte {
+	return append(buf, tlv.MAC...)
+}
+
+func (tlv *DeviceMAC) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len != 0 && len != 6 {
+		return fmt.Errorf("unexpected device MAC length: %d", len)
+	}
+	tlv.MAC = append(tlv.MAC[:0], value...)
+	return nil
+}
+
 func (tlv *DeviceMAC) String() string {
 	return fmt.Sprintf("DeviceMAC(%04xh) %s", TypeDeviceMAC, tlv.MAC)
 }
+
+func (tlv *DeviceMAC) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DeviceMAC) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DeviceMAC) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDeviceMAC, tlv)
+}
+
+type deviceMACJSON struct {
+	MAC string `json:"mac"`
+}
+
+func (tlv *DeviceMAC) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDeviceMAC, &deviceMACJSON{MAC: tlv.MAC.String()})
+}
+
+func (tlv *DeviceMAC) UnmarshalJSON(data []byte) error {
+	decoded := &deviceMACJSON{}
+	err := unmarshalTLVJSON(data, TypeDeviceMAC, decoded)
+	if err != nil {
+		return err
+	}
+	mac, err := parseJSONMAC(decoded.MAC)
+	if err != nil {
+		return err
+	}
+	tlv.MAC = mac
+	return nil
+}
@@ -15,7 +15,7 @@ import (
 //
 // Add an empty DeviceName TLV to a read request to get a filled one back.
 type DeviceName struct {
-	Name string // Device Name
+	Name string `json:"name"` // Device Name
 }
 
 func EmptyDeviceName() *DeviceName {
@@ -42,6 +42,39 @@ func (tlv *DeviceName) Value() []byte {
 	return []byte(tlv.Name)
 }
 
+func (tlv *DeviceName) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Name...)
+}
+
+func (tlv *DeviceName) unmarshalValue(value []byte) error {
+	if tlv.Name != string(value) {
+		tlv.Name = string(value)
+	}
+	return nil
+}
+
 func (tlv *DeviceName) String() string {
 	return fmt.Sprintf("DeviceName(%04xh) '%s'", TypeDeviceName, tlv.Name)
 }
+
+func (tlv *DeviceName) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DeviceName) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DeviceName) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDeviceName, tlv)
+}
+
+type deviceNameJSON DeviceName
+
+func (tlv *DeviceName) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDeviceName, (*deviceNameJSON)(tlv))
+}
+
+func (tlv *DeviceName) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeDeviceName, (*deviceNameJSON)(tlv))
+}
@@ -28,14 +28,12 @@ func NewDeviceNetmask(netmask net.IP) *DeviceNetmask {
 }
 
 func unmarshalDeviceNetmask(bytes []byte) (*DeviceNetmask, error) {
-	len := len(bytes)
-	if len == 0 {
-		return EmptyDeviceNetmask(), nil
+	tlv := EmptyDeviceNetmask()
+	err := tlv.unmarshalValue(bytes)
+	if err != nil {
+		return nil, err
 	}
-	if len != 4 && len != 16 {
-		return nil, fmt.Errorf("unexpected device netmask length: %d", len)
-	}
-	return NewDeviceNetmask(net.IP(bytes)), nil
+	return tlv, nil
 }
 
 func (tlv *DeviceNetmask) Type() Type {
@@ -50,6 +48,53 @@ func (tlv *DeviceNetmask) Value() []byte {
 	return tlv.Netmask
 }
 
+func (tlv *DeviceNetmask) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Netmask...)
+}
+
+func (tlv *DeviceNetmask) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len != 0 && len != 4 && len != 16 {
+		return fmt.Errorf("unexpected device netmask length: %d", len)
+	}
+	tlv.Netmask = append(tlv.Netmask[:0], value...)
+	return nil
+}
+
 func (tlv *DeviceNetmask) String() string {
 	return fmt.Sprintf("DeviceNetmask(%04xh) %s", TypeDeviceNetmask, tlv.Netmask)
 }
+
+func (tlv *DeviceNetmask) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DeviceNetmask) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DeviceNetmask) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDeviceNetmask, tlv)
+}
+
+type deviceNetmaskJSON struct {
+	Netmask string `json:"netmask"`
+}
+
+func (tlv *DeviceNetmask) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDeviceNetmask, &deviceNetmaskJSON{Netmask: formatJSONIP(tlv.Netmask)})
+}
+
+func (tlv *DeviceNetmask) UnmarshalJSON(data []byte) error {
+	decoded := &deviceNetmaskJSON{}
+	err := unmarshalTLVJSON(data, TypeDeviceNetmask, decoded)
+	if err != nil {
+		return err
+	}
+	ip, err := parseJSONIP(decoded.Netmask)
+	if err != nil {
+		return err
+	}
+	tlv.Netmask = ip
+	return nil
+}
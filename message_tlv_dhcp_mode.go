@@ -1,4 +1,4 @@
-// message_tlv_port_statistic.go
+// message_tlv_dhcp_mode.go
 //
 // Copyright (C) 2022-2024 Holger de Carne
 //
@@ -15,28 +15,54 @@ import (
 //
 // Add an empty DHCPMode TLV to a read request to get a filled one back.
 type DHCPMode struct {
-	Mode uint8 // DHCP mode (0: disabled, 1: enabled)
+	Mode DHCPModeValue `json:"mode"` // DHCP mode
+}
+
+// DHCPModeValue defines the DHCP modes.
+//
+// DHCPRenew is only valid within a write request. It triggers a DHCP lease renew on the target device.
+type DHCPModeValue uint8
+
+const (
+	DHCPDisabled DHCPModeValue = 0x00
+	DHCPEnabled  DHCPModeValue = 0x01
+	DHCPRenew    DHCPModeValue = 0x02
+)
+
+// String returns a textual representation of the mode value.
+func (mode DHCPModeValue) String() string {
+	switch mode {
+	case DHCPDisabled:
+		return "Disabled"
+	case DHCPEnabled:
+		return "Enabled"
+	case DHCPRenew:
+		return "Renew"
+	}
+	return fmt.Sprintf("%02xh", uint8(mode))
 }
 
 const dhcpModeLen uint16 = 1
 
 func EmptyDHCPMode() *DHCPMode {
-	return NewDHCPMode(0)
+	return NewDHCPMode(DHCPDisabled)
 }
 
-func NewDHCPMode(mode uint8) *DHCPMode {
+// NewDHCPMode creates a DHCPMode TLV for the given mode.
+//
+// Compatibility note: the mode parameter (as well as the DHCPMode.Mode field) used to be of type uint8. Untyped
+// constants are still accepted as is, but uint8 variables have to be converted to DHCPModeValue.
+func NewDHCPMode(mode DHCPModeValue) *DHCPMode {
 	return &DHCPMode{Mode: mode}
 }
 
 func unmarshalDHCPMode(value []byte) (*DHCPMode, error) {
-	len := len(value)
-	if len == 0 {
-		return EmptyDHCPMode(), nil
+	tlv := EmptyDHCPMode()
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
 	}
-	if len != int(dhcpModeLen) {
-		return nil, fmt.Errorf("unexpected dhcp mode length: %d", len)
-	}
-	return NewDHCPMode(value[0]), nil
+	return tlv, nil
 }
 
 func (tlv *DHCPMode) Type() Type {
@@ -48,22 +74,60 @@ func (tlv *DHCPMode) Length() uint16 {
 }
 
 func (tlv *DHCPMode) Value() []byte {
-	value := make([]byte, dhcpModeLen)
-	value[0] = tlv.Mode
-	return value
+	return tlv.appendValue(make([]byte, 0, dhcpModeLen))
+}
+
+func (tlv *DHCPMode) appendValue(buf []byte) []byte {
+	return append(buf, byte(tlv.Mode))
+}
+
+func (tlv *DHCPMode) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		tlv.Mode = DHCPDisabled
+		return nil
+	}
+	if len != int(dhcpModeLen) {
+		return fmt.Errorf("unexpected dhcp mode length: %d", len)
+	}
+	tlv.Mode = DHCPModeValue(value[0])
+	return nil
 }
 
 func (tlv *DHCPMode) String() string {
 	return fmt.Sprintf("DHCPMode(%04xh) %s", TypeDHCPMode, tlv.ModeString())
 }
 
+func (tlv *DHCPMode) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *DHCPMode) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *DHCPMode) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeDHCPMode, tlv)
+}
+
 // ModeString returns a textual representation of the mode value.
 func (tlv *DHCPMode) ModeString() string {
-	switch tlv.Mode {
-	case 0:
-		return "Disabled"
-	case 1:
-		return "Enabled"
+	return tlv.Mode.String()
+}
+
+func (tlv *DHCPMode) validate(operation OperationCode) error {
+	if operation == WriteRequest && tlv.Mode != DHCPDisabled && tlv.Mode != DHCPEnabled && tlv.Mode != DHCPRenew {
+		return fmt.Errorf("invalid DHCP mode: %s", tlv.Mode)
 	}
-	return fmt.Sprintf("%02xh", tlv.Mode)
+	return nil
+}
+
+type dhcpModeJSON DHCPMode
+
+func (tlv *DHCPMode) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeDHCPMode, (*dhcpModeJSON)(tlv))
+}
+
+func (tlv *DHCPMode) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeDHCPMode, (*dhcpModeJSON)(tlv))
 }
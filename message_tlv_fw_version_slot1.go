@@ -15,7 +15,7 @@ import (
 //
 // Add an empty FWVersionSlot1 TLV to a read request to get a filled one back.
 type FWVersionSlot1 struct {
-	Version string // Slot 1 version (e.g. 2.06.17)
+	Version string `json:"version"` // Slot 1 version (e.g. 2.06.17)
 }
 
 func EmptyFWVersionSlot1() *FWVersionSlot1 {
@@ -42,6 +42,39 @@ func (tlv *FWVersionSlot1) Value() []byte {
 	return []byte(tlv.Version)
 }
 
+func (tlv *FWVersionSlot1) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Version...)
+}
+
+func (tlv *FWVersionSlot1) unmarshalValue(value []byte) error {
+	if tlv.Version != string(value) {
+		tlv.Version = string(value)
+	}
+	return nil
+}
+
 func (tlv *FWVersionSlot1) String() string {
 	return fmt.Sprintf("FWVersionSlot1(%04xh) '%s'", TypeFWVersionSlot1, tlv.Version)
 }
+
+func (tlv *FWVersionSlot1) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *FWVersionSlot1) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *FWVersionSlot1) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeFWVersionSlot1, tlv)
+}
+
+type fwVersionSlot1JSON FWVersionSlot1
+
+func (tlv *FWVersionSlot1) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeFWVersionSlot1, (*fwVersionSlot1JSON)(tlv))
+}
+
+func (tlv *FWVersionSlot1) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeFWVersionSlot1, (*fwVersionSlot1JSON)(tlv))
+}
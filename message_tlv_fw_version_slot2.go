@@ -15,7 +15,7 @@ import (
 //
 // Add an empty FWVersionSlot2 TLV to a read request to get a filled one back.
 type FWVersionSlot2 struct {
-	Version string // Slot 2 version (e.g. 2.06.17)
+	Version string `json:"version"` // Slot 2 version (e.g. 2.06.17)
 }
 
 func EmptyFWVersionSlot2() *FWVersionSlot2 {
@@ -42,6 +42,39 @@ func (tlv *FWVersionSlot2) Value() []byte {
 	return []byte(tlv.Version)
 }
 
+func (tlv *FWVersionSlot2) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Version...)
+}
+
+func (tlv *FWVersionSlot2) unmarshalValue(value []byte) error {
+	if tlv.Version != string(value) {
+		tlv.Version = string(value)
+	}
+	return nil
+}
+
 func (tlv *FWVersionSlot2) String() string {
 	return fmt.Sprintf("FWVersionSlot2(%04xh) '%s'", TypeFWVersionSlot2, tlv.Version)
 }
+
+func (tlv *FWVersionSlot2) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *FWVersionSlot2) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *FWVersionSlot2) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeFWVersionSlot2, tlv)
+}
+
+type fwVersionSlot2JSON FWVersionSlot2
+
+func (tlv *FWVersionSlot2) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeFWVersionSlot2, (*fwVersionSlot2JSON)(tlv))
+}
+
+func (tlv *FWVersionSlot2) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeFWVersionSlot2, (*fwVersionSlot2JSON)(tlv))
+}
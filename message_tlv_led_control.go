@@ -0,0 +1,122 @@
+// message_tlv_led_control.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"fmt"
+)
+
+// TLV to exchange the target device's front panel LED mode.
+//
+// Add an empty LEDControl TLV to a read request to get a filled one back.
+//
+// Note: Neither the type code TypeLEDControl (9800h) nor the mode values are backed by a
+// captured device exchange yet; verify them against the target device before relying on them.
+type LEDControl struct {
+	Mode LEDMode `json:"mode"` // LED mode
+}
+
+// LEDMode defines the front panel LED modes.
+type LEDMode uint8
+
+const (
+	LEDModeOn  LEDMode = 0x00 // All LEDs on
+	LEDModeOff LEDMode = 0x01 // All LEDs off
+)
+
+// String returns a textual representation of the mode value.
+func (mode LEDMode) String() string {
+	switch mode {
+	case LEDModeOn:
+		return "On"
+	case LEDModeOff:
+		return "Off"
+	}
+	return fmt.Sprintf("%02xh", uint8(mode))
+}
+
+const ledControlLen uint16 = 1
+
+func EmptyLEDControl() *LEDControl {
+	return NewLEDControl(LEDModeOn)
+}
+
+func NewLEDControl(mode LEDMode) *LEDControl {
+	return &LEDControl{Mode: mode}
+}
+
+func unmarshalLEDControl(value []byte) (*LEDControl, error) {
+	tlv := EmptyLEDControl()
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return tlv, nil
+}
+
+func (tlv *LEDControl) Type() Type {
+	return TypeLEDControl
+}
+
+func (tlv *LEDControl) Length() uint16 {
+	return uint16(ledControlLen)
+}
+
+func (tlv *LEDControl) Value() []byte {
+	return tlv.appendValue(make([]byte, 0, ledControlLen))
+}
+
+func (tlv *LEDControl) appendValue(buf []byte) []byte {
+	return append(buf, byte(tlv.Mode))
+}
+
+func (tlv *LEDControl) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		tlv.Mode = LEDModeOn
+		return nil
+	}
+	if len != int(ledControlLen) {
+		return fmt.Errorf("unexpected LED mode length: %d", len)
+	}
+	tlv.Mode = LEDMode(value[0])
+	return nil
+}
+
+func (tlv *LEDControl) String() string {
+	return fmt.Sprintf("LEDControl(%04xh) %s", TypeLEDControl, tlv.Mode)
+}
+
+func (tlv *LEDControl) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *LEDControl) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *LEDControl) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeLEDControl, tlv)
+}
+
+func (tlv *LEDControl) validate(operation OperationCode) error {
+	if operation == WriteRequest && tlv.Mode != LEDModeOn && tlv.Mode != LEDModeOff {
+		return fmt.Errorf("invalid LED mode: %s", tlv.Mode)
+	}
+	return nil
+}
+
+type ledControlJSON LEDControl
+
+func (tlv *LEDControl) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeLEDControl, (*ledControlJSON)(tlv))
+}
+
+func (tlv *LEDControl) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeLEDControl, (*ledControlJSON)(tlv))
+}
@@ -15,7 +15,7 @@ import (
 //
 // Add an empty NextFWSlot TLV to a read request to get a filled one back.
 type NextFWSlot struct {
-	Slot uint8 // The slot (1 or 2) to use for next boot
+	Slot uint8 `json:"slot"` // The slot (1 or 2) to use for next boot
 }
 
 const nextFWSlotLen uint16 = 1
@@ -29,14 +29,12 @@ func NewNextFWSlot(slot uint8) *NextFWSlot {
 }
 
 func unmarshalNextFWSlot(value []byte) (*NextFWSlot, error) {
-	len := len(value)
-	if len == 0 {
-		return EmptyNextFWSlot(), nil
-	}
-	if len != int(nextFWSlotLen) {
-		return nil, fmt.Errorf("unexpected slot length: %d", len)
+	tlv := EmptyNextFWSlot()
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
 	}
-	return NewNextFWSlot(value[0]), nil
+	return tlv, nil
 }
 
 func (tlv *NextFWSlot) Type() Type {
@@ -48,11 +46,48 @@ func (tlv *NextFWSlot) Length() uint16 {
 }
 
 func (tlv *NextFWSlot) Value() []byte {
-	value := make([]byte, nextFWSlotLen)
-	value[0] = tlv.Slot
-	return value
+	return tlv.appendValue(make([]byte, 0, nextFWSlotLen))
+}
+
+func (tlv *NextFWSlot) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Slot)
+}
+
+func (tlv *NextFWSlot) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		tlv.Slot = 0
+		return nil
+	}
+	if len != int(nextFWSlotLen) {
+		return fmt.Errorf("unexpected slot length: %d", len)
+	}
+	tlv.Slot = value[0]
+	return nil
 }
 
 func (tlv *NextFWSlot) String() string {
 	return fmt.Sprintf("NextFWSlot(%04xh) %d", TypeNextFWSlot, tlv.Slot)
 }
+
+func (tlv *NextFWSlot) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *NextFWSlot) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *NextFWSlot) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeNextFWSlot, tlv)
+}
+
+type nextFWSlotJSON NextFWSlot
+
+func (tlv *NextFWSlot) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeNextFWSlot, (*nextFWSlotJSON)(tlv))
+}
+
+func (tlv *NextFWSlot) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypeNextFWSlot, (*nextFWSlotJSON)(tlv))
+}
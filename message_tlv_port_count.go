@@ -0,0 +1,93 @@
+// message_tlv_port_count.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"fmt"
+)
+
+// TLV to exchange the target device's number of ports.
+//
+// Add an empty PortCount TLV to a read request to get a filled one back.
+type PortCount struct {
+	Count uint8 `json:"count"` // Number of ports
+}
+
+const portCountLen uint16 = 1
+
+func EmptyPortCount() *PortCount {
+	return NewPortCount(0)
+}
+
+func NewPortCount(count uint8) *PortCount {
+	return &PortCount{Count: count}
+}
+
+func unmarshalPortCount(value []byte) (*PortCount, error) {
+	tlv := EmptyPortCount()
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return tlv, nil
+}
+
+func (tlv *PortCount) Type() Type {
+	return TypePortCount
+}
+
+func (tlv *PortCount) Length() uint16 {
+	return uint16(portCountLen)
+}
+
+func (tlv *PortCount) Value() []byte {
+	return tlv.appendValue(make([]byte, 0, portCountLen))
+}
+
+func (tlv *PortCount) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Count)
+}
+
+func (tlv *PortCount) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		tlv.Count = 0
+		return nil
+	}
+	if len != int(portCountLen) {
+		return fmt.Errorf("unexpected port count length: %d", len)
+	}
+	tlv.Count = value[0]
+	return nil
+}
+
+func (tlv *PortCount) String() string {
+	return fmt.Sprintf("PortCount(%04xh) %d", TypePortCount, tlv.Count)
+}
+
+func (tlv *PortCount) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *PortCount) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *PortCount) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypePortCount, tlv)
+}
+
+type portCountJSON PortCount
+
+func (tlv *PortCount) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypePortCount, (*portCountJSON)(tlv))
+}
+
+func (tlv *PortCount) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypePortCount, (*portCountJSON)(tlv))
+}
@@ -8,7 +8,6 @@
 package nsdp
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 )
@@ -17,13 +16,13 @@ import (
 //
 // Add an empty PortStatistic TLV to a read request to receive a filled one for each of the device's port.
 type PortStatistic struct {
-	Port       uint8  // The number of the port this statistic refers to
-	Received   uint64 // Number of received bytes
-	Sent       uint64 // Number of sent bytes
-	Packets    uint64 // Number of processed packets
-	Broadcasts uint64 // Number of processed broadcasts
-	Multicasts uint64 // Number of processed multicasts
-	Errors     uint64 // Number of encountered errors
+	Port       uint8  `json:"port"`       // The number of the port this statistic refers to
+	Received   uint64 `json:"received"`   // Number of received bytes
+	Sent       uint64 `json:"sent"`       // Number of sent bytes
+	Packets    uint64 `json:"packets"`    // Number of processed packets
+	Broadcasts uint64 `json:"broadcasts"` // Number of processed broadcasts
+	Multicasts uint64 `json:"multicasts"` // Number of processed multicasts
+	Errors     uint64 `json:"errors"`     // Number of encountered errors
 }
 
 const portStatisticLen uint16 = 49
@@ -45,25 +44,19 @@ func NewPortStatistic(port uint8, received uint64, sent uint64, packets uint64,
 }
 
 func unmarshalPortStatistic(value []byte) (*PortStatistic, error) {
-	len := len(value)
-	if len == 0 {
-		return EmptyPortStatistic(), nil
-	}
-	if len != int(portStatisticLen) {
-		return nil, fmt.Errorf("unexpected port statistic length: %d", len)
-	}
-	buffer := bytes.NewBuffer(value)
 	tlv := EmptyPortStatistic()
-	tlv.Port, _ = buffer.ReadByte()
-	binary.Read(buffer, binary.BigEndian, &tlv.Received)
-	binary.Read(buffer, binary.BigEndian, &tlv.Sent)
-	binary.Read(buffer, binary.BigEndian, &tlv.Packets)
-	binary.Read(buffer, binary.BigEndian, &tlv.Broadcasts)
-	binary.Read(buffer, binary.BigEndian, &tlv.Multicasts)
-	binary.Read(buffer, binary.BigEndian, &tlv.Errors)
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
 	return tlv, nil
 }
 
+// PortNumber gets the number of the port this TLV refers to.
+func (tlv *PortStatistic) PortNumber() uint8 {
+	return tlv.Port
+}
+
 func (tlv *PortStatistic) Type() Type {
 	return TypePortStatistic
 }
@@ -73,18 +66,60 @@ func (tlv *PortStatistic) Length() uint16 {
 }
 
 func (tlv *PortStatistic) Value() []byte {
-	buffer := &bytes.Buffer{}
-	buffer.Grow(int(portStatisticLen))
-	buffer.WriteByte(tlv.Port)
-	binary.Write(buffer, binary.BigEndian, tlv.Received)
-	binary.Write(buffer, binary.BigEndian, tlv.Sent)
-	binary.Write(buffer, binary.BigEndian, tlv.Packets)
-	binary.Write(buffer, binary.BigEndian, tlv.Broadcasts)
-	binary.Write(buffer, binary.BigEndian, tlv.Multicasts)
-	binary.Write(buffer, binary.BigEndian, tlv.Errors)
-	return buffer.Bytes()
+	return tlv.appendValue(make([]byte, 0, portStatisticLen))
+}
+
+func (tlv *PortStatistic) appendValue(buf []byte) []byte {
+	buf = append(buf, tlv.Port)
+	buf = binary.BigEndian.AppendUint64(buf, tlv.Received)
+	buf = binary.BigEndian.AppendUint64(buf, tlv.Sent)
+	buf = binary.BigEndian.AppendUint64(buf, tlv.Packets)
+	buf = binary.BigEndian.AppendUint64(buf, tlv.Broadcasts)
+	buf = binary.BigEndian.AppendUint64(buf, tlv.Multicasts)
+	return binary.BigEndian.AppendUint64(buf, tlv.Errors)
+}
+
+func (tlv *PortStatistic) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		*tlv = PortStatistic{}
+		return nil
+	}
+	if len != int(portStatisticLen) {
+		return fmt.Errorf("unexpected port statistic length: %d", len)
+	}
+	tlv.Port = value[0]
+	tlv.Received = binary.BigEndian.Uint64(value[1:9])
+	tlv.Sent = binary.BigEndian.Uint64(value[9:17])
+	tlv.Packets = binary.BigEndian.Uint64(value[17:25])
+	tlv.Broadcasts = binary.BigEndian.Uint64(value[25:33])
+	tlv.Multicasts = binary.BigEndian.Uint64(value[33:41])
+	tlv.Errors = binary.BigEndian.Uint64(value[41:49])
+	return nil
 }
 
 func (tlv *PortStatistic) String() string {
 	return fmt.Sprintf("PortStatistic(%04xh) Port%d Received: %d, Sent: %d, Packets: %d, Broadcasts: %d, Multicasts: %d, Errors: %d", TypePortStatistic, tlv.Port, tlv.Received, tlv.Sent, tlv.Packets, tlv.Broadcasts, tlv.Multicasts, tlv.Errors)
 }
+
+func (tlv *PortStatistic) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *PortStatistic) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *PortStatistic) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypePortStatistic, tlv)
+}
+
+type portStatisticJSON PortStatistic
+
+func (tlv *PortStatistic) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypePortStatistic, (*portStatisticJSON)(tlv))
+}
+
+func (tlv *PortStatistic) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypePortStatistic, (*portStatisticJSON)(tlv))
+}
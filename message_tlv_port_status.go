@@ -1,4 +1,4 @@
-// message_tlv_port_statistic.go
+// message_tlv_port_status.go
 //
 // Copyright (C) 2022-2024 Holger de Carne
 //
@@ -8,7 +8,6 @@
 package nsdp
 
 import (
-	"bytes"
 	"fmt"
 )
 
@@ -16,9 +15,74 @@ import (
 //
 // Add an empty PortStatus TLV to a read request to receive a filled one for each of the device's port.
 type PortStatus struct {
-	Port     uint8 // The number of the port this status refers to
-	Status   uint8 // The port's status (0: down, otherwise up)
-	Unknown1 uint8
+	Port     uint8      `json:"port"`     // The number of the port this status refers to
+	Status   LinkStatus `json:"status"`   // The port's link status
+	Unknown1 uint8      `json:"unknown1"` // The port's flags (see FlowControl; remaining bits are unknown)
+}
+
+// LinkStatus defines the link states (speed and duplex mode) reported for a port.
+//
+// The multi-gig states Link10GFull, Link2500MFull and Link5GFull are not backed by a captured device exchange yet.
+// Unrecognized states are still reported via their raw value.
+type LinkStatus uint8
+
+const (
+	LinkDown      LinkStatus = 0x00
+	Link10MHalf   LinkStatus = 0x01
+	Link10MFull   LinkStatus = 0x02
+	Link100MHalf  LinkStatus = 0x03
+	Link100MFull  LinkStatus = 0x04
+	Link1GFull    LinkStatus = 0x05
+	Link10GFull   LinkStatus = 0x06 // Multi-gig models only (e.g. XS708E)
+	Link2500MFull LinkStatus = 0x07 // Multi-gig models only (e.g. GS110EMX)
+	Link5GFull    LinkStatus = 0x08 // Multi-gig models only (e.g. GS110EMX)
+)
+
+// Flow control flag within PortStatus.Unknown1 (not backed by a captured device exchange yet)
+const portStatusFlowControl uint8 = 0x01
+
+type linkStatusInfo struct {
+	speed      uint
+	fullDuplex bool
+	text       string
+}
+
+var linkStatusInfos = map[LinkStatus]linkStatusInfo{
+	LinkDown:      {speed: 0, fullDuplex: false, text: "Disconnected"},
+	Link10MHalf:   {speed: 10, fullDuplex: false, text: "10Mbit/half-duplex"},
+	Link10MFull:   {speed: 10, fullDuplex: true, text: "10Mbit/full-duplex"},
+	Link100MHalf:  {speed: 100, fullDuplex: false, text: "100Mbit/half-duplex"},
+	Link100MFull:  {speed: 100, fullDuplex: true, text: "100Mbit/full-duplex"},
+	Link1GFull:    {speed: 1000, fullDuplex: true, text: "1Gbit/full-duplex"},
+	Link10GFull:   {speed: 10000, fullDuplex: true, text: "10Gbit/full-duplex"},
+	Link2500MFull: {speed: 2500, fullDuplex: true, text: "2.5Gbit/full-duplex"},
+	Link5GFull:    {speed: 5000, fullDuplex: true, text: "5Gbit/full-duplex"},
+}
+
+// Up checks whether the link is up.
+//
+// Unrecognized link states are considered up, as only LinkDown indicates a disconnected port.
+func (status LinkStatus) Up() bool {
+	return status != LinkDown
+}
+
+// Speed gets the link speed in Mbit/s (0 if the link is down or the status is not recognized).
+func (status LinkStatus) Speed() uint {
+	return linkStatusInfos[status].speed
+}
+
+// FullDuplex checks whether the link is operating in full-duplex mode.
+func (status LinkStatus) FullDuplex() bool {
+	return linkStatusInfos[status].fullDuplex
+}
+
+// String returns a textual representation of the status value.
+func (status LinkStatus) String() string {
+	info, ok := linkStatusInfos[status]
+	if !ok {
+		return fmt.Sprintf("%02xh", uint8(status))
+	}
+	return info.text
 }
 
 const portStatusLen uint16 = 3
@@ -27,7 +91,11 @@ func EmptyPortStatus() *PortStatus {
 	return &PortStatus{}
 }
 
-func NewPortStatus(port, status uint8) *PortStatus {
+// NewPortStatus creates a PortStatus TLV for the given port and link status.
+//
+// Compatibility note: the status parameter (as well as the PortStatus.Status field) used to be of type uint8. Untyped
+// constants are still accepted as is, but uint8 variables have to be converted to LinkStatus.
+func NewPortStatus(port uint8, status LinkStatus) *PortStatus {
 	return &PortStatus{
 		Port:   port,
 		Status: status,
@@ -35,21 +103,19 @@ func NewPortStatus(port, status uint8) *PortStatus {
 }
 
 func unmarshalPortStatus(value []byte) (*PortStatus, error) {
-	len := len(value)
-	if len == 0 {
-		return EmptyPortStatus(), nil
-	}
-	if len != int(portStatusLen) {
-		return nil, fmt.Errorf("unexpected port status length: %d", len)
-	}
-	buffer := bytes.NewBuffer(value)
 	tlv := EmptyPortStatus()
-	tlv.Port, _ = buffer.ReadByte()
-	tlv.Status, _ = buffer.ReadByte()
-	tlv.Unknown1, _ = buffer.ReadByte()
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
 	return tlv, nil
 }
 
+// PortNumber gets the number of the port this TLV refers to.
+func (tlv *PortStatus) PortNumber() uint8 {
+	return tlv.Port
+}
+
 func (tlv *PortStatus) Type() Type {
 	return TypePortStatus
 }
@@ -59,33 +125,62 @@ func (tlv *PortStatus) Length() uint16 {
 }
 
 func (tlv *PortStatus) Value() []byte {
-	buffer := &bytes.Buffer{}
-	buffer.Grow(int(portStatusLen))
-	buffer.WriteByte(tlv.Port)
-	buffer.WriteByte(tlv.Status)
-	buffer.WriteByte(tlv.Unknown1)
-	return buffer.Bytes()
+	return tlv.appendValue(make([]byte, 0, portStatusLen))
+}
+
+func (tlv *PortStatus) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Port, byte(tlv.Status), tlv.Unknown1)
+}
+
+func (tlv *PortStatus) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		*tlv = PortStatus{}
+		return nil
+	}
+	if len != int(portStatusLen) {
+		return fmt.Errorf("unexpected port status length: %d", len)
+	}
+	tlv.Port = value[0]
+	tlv.Status = LinkStatus(value[1])
+	tlv.Unknown1 = value[2]
+	return nil
 }
 
 func (tlv *PortStatus) String() string {
 	return fmt.Sprintf("PortStatus(%04xh) Port%d Status: %s Unknown1: %02xh", TypePortStatus, tlv.Port, tlv.StatusString(), tlv.Unknown1)
 }
 
+func (tlv *PortStatus) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *PortStatus) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *PortStatus) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypePortStatus, tlv)
+}
+
 // StatusString returns a textual representation of the status value.
 func (tlv *PortStatus) StatusString() string {
-	switch tlv.Status {
-	case 0:
-		return "Disconnected"
-	case 1:
-		return "10Mbit/half-duplex"
-	case 2:
-		return "10Mbit/full-duplex"
-	case 3:
-		return "100Mbit/half-duplex"
-	case 4:
-		return "100Mbit/full-duplex"
-	case 5:
-		return "1Gbit/full-duplex"
-	}
-	return fmt.Sprintf("%02xh", tlv.Status)
+	return tlv.Status.String()
+}
+
+// FlowControl checks whether flow control is enabled for the port.
+//
+// The flag's position has not been verified against a captured device exchange yet.
+func (tlv *PortStatus) FlowControl() bool {
+	return tlv.Unknown1&portStatusFlowControl != 0
+}
+
+type portStatusJSON PortStatus
+
+func (tlv *PortStatus) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypePortStatus, (*portStatusJSON)(tlv))
+}
+
+func (tlv *PortStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypePortStatus, (*portStatusJSON)(tlv))
 }
@@ -0,0 +1,122 @@
+// message_tlv_power_saving.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"fmt"
+)
+
+// TLV to exchange the target device's power saving (Green Ethernet) mode.
+//
+// Add an empty PowerSaving TLV to a read request to get a filled one back.
+//
+// Note: Neither the type code TypePowerSaving (9400h) nor the mode values are backed by a
+// captured device exchange yet; verify them against the target device before relying on them.
+type PowerSaving struct {
+	Mode PowerSavingMode `json:"mode"` // Power saving mode
+}
+
+// PowerSavingMode defines the power saving (Green Ethernet) modes.
+type PowerSavingMode uint8
+
+const (
+	PowerSavingDisabled PowerSavingMode = 0x00
+	PowerSavingEnabled  PowerSavingMode = 0x01
+)
+
+// String returns a textual representation of the mode value.
+func (mode PowerSavingMode) String() string {
+	switch mode {
+	case PowerSavingDisabled:
+		return "Disabled"
+	case PowerSavingEnabled:
+		return "Enabled"
+	}
+	return fmt.Sprintf("%02xh", uint8(mode))
+}
+
+const powerSavingLen uint16 = 1
+
+func EmptyPowerSaving() *PowerSaving {
+	return NewPowerSaving(PowerSavingDisabled)
+}
+
+func NewPowerSaving(mode PowerSavingMode) *PowerSaving {
+	return &PowerSaving{Mode: mode}
+}
+
+func unmarshalPowerSaving(value []byte) (*PowerSaving, error) {
+	tlv := EmptyPowerSaving()
+	err := tlv.unmarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return tlv, nil
+}
+
+func (tlv *PowerSaving) Type() Type {
+	return TypePowerSaving
+}
+
+func (tlv *PowerSaving) Length() uint16 {
+	return uint16(powerSavingLen)
+}
+
+func (tlv *PowerSaving) Value() []byte {
+	return tlv.appendValue(make([]byte, 0, powerSavingLen))
+}
+
+func (tlv *PowerSaving) appendValue(buf []byte) []byte {
+	return append(buf, byte(tlv.Mode))
+}
+
+func (tlv *PowerSaving) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len == 0 {
+		tlv.Mode = PowerSavingDisabled
+		return nil
+	}
+	if len != int(powerSavingLen) {
+		return fmt.Errorf("unexpected power saving mode length: %d", len)
+	}
+	tlv.Mode = PowerSavingMode(value[0])
+	return nil
+}
+
+func (tlv *PowerSaving) String() string {
+	return fmt.Sprintf("PowerSaving(%04xh) %s", TypePowerSaving, tlv.Mode)
+}
+
+func (tlv *PowerSaving) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *PowerSaving) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *PowerSaving) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypePowerSaving, tlv)
+}
+
+func (tlv *PowerSaving) validate(operation OperationCode) error {
+	if operation == WriteRequest && tlv.Mode != PowerSavingDisabled && tlv.Mode != PowerSavingEnabled {
+		return fmt.Errorf("invalid power saving mode: %s", tlv.Mode)
+	}
+	return nil
+}
+
+type powerSavingJSON PowerSaving
+
+func (tlv *PowerSaving) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypePowerSaving, (*powerSavingJSON)(tlv))
+}
+
+func (tlv *PowerSaving) UnmarshalJSON(data []byte) error {
+	return unmarshalTLVJSON(data, TypePowerSaving, (*powerSavingJSON)(tlv))
+}
@@ -0,0 +1,96 @@
+// message_tlv_raw.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// TLV preserving a TLV of unrecognized type.
+//
+// Unless strict decoding is requested, TLVs of unrecognized type are decoded into a RawTLV, which keeps
+// the TLV's type code and value as is and re-encodes them byte for byte.
+type RawTLV struct {
+	RawType Type   // The TLV's type code
+	Data    []byte // The TLV's raw value
+}
+
+func NewRawTLV(tlvType Type, data []byte) *RawTLV {
+	return &RawTLV{RawType: tlvType, Data: data}
+}
+
+func (tlv *RawTLV) Type() Type {
+	return tlv.RawType
+}
+
+func (tlv *RawTLV) Length() uint16 {
+	return uint16(len(tlv.Data))
+}
+
+func (tlv *RawTLV) Value() []byte {
+	return tlv.Data
+}
+
+func (tlv *RawTLV) appendValue(buf []byte) []byte {
+	return append(buf, tlv.Data...)
+}
+
+func (tlv *RawTLV) unmarshalValue(value []byte) error {
+	tlv.Data = append(tlv.Data[:0], value...)
+	return nil
+}
+
+func (tlv *RawTLV) String() string {
+	return fmt.Sprintf("RawTLV(%04xh) %s", tlv.RawType, hex.EncodeToString(tlv.Data))
+}
+
+func (tlv *RawTLV) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *RawTLV) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+// UnmarshalBinary decodes the TLV including its type and length prefix. Any type code is accepted.
+func (tlv *RawTLV) UnmarshalBinary(data []byte) error {
+	tlvType, value, err := decodeTLVBinary(data)
+	if err != nil {
+		return err
+	}
+	tlv.RawType = tlvType
+	return tlv.unmarshalValue(value)
+}
+
+type rawTLVJSON struct {
+	Data string `json:"data"`
+}
+
+func (tlv *RawTLV) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(tlv.RawType, &rawTLVJSON{Data: hex.EncodeToString(tlv.Data)})
+}
+
+func (tlv *RawTLV) UnmarshalJSON(data []byte) error {
+	tlvType, err := peekTLVJSONType(data)
+	if err != nil {
+		return err
+	}
+	decoded := &rawTLVJSON{}
+	err = unmarshalTLVJSON(data, tlvType, decoded)
+	if err != nil {
+		return err
+	}
+	value, err := hex.DecodeString(decoded.Data)
+	if err != nil {
+		return fmt.Errorf("invalid raw TLV data; cause: %w", err)
+	}
+	tlv.RawType = tlvType
+	tlv.Data = value
+	return nil
+}
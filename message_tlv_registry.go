@@ -0,0 +1,133 @@
+// message_tlv_registry.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// TLVDecoder decodes the value of a specific TLV type.
+//
+// A decoder must accept an empty value (as received within read requests) and return an empty TLV in this case.
+type TLVDecoder func(value []byte) (TLV, error)
+
+type tlvRegistration struct {
+	decoder TLVDecoder
+	builtin bool // Set for the built-in decoders (enabling in-place decoding; see Decoder)
+}
+
+var tlvRegistry = struct {
+	sync.RWMutex
+	registrations map[Type]tlvRegistration
+}{
+	registrations: make(map[Type]tlvRegistration),
+}
+
+// Built-in registrations by TLV type (immutable after init)
+var builtinTLVRegistrations = make(map[Type]tlvRegistration)
+
+// RegisterTLV registers a decoder for the given TLV type.
+//
+// A decoder already registered for the same type (including the built-in ones) is replaced. The registered decoder is
+// invoked for every TLV of this type, even if it has been obtained via LookupTLVDecoder for a built-in type. Use
+// ResetTLV to restore the built-in handling. Registering a decoder for the EOM type or a nil decoder causes a panic.
+// RegisterTLV is safe for concurrent use.
+func RegisterTLV(tlvType Type, decoder TLVDecoder) {
+	if tlvType == TypeEOM {
+		panic(fmt.Sprintf("nsdp: cannot register decoder for reserved TLV type %04xh", tlvType))
+	}
+	if decoder == nil {
+		panic(fmt.Sprintf("nsdp: nil decoder for TLV type %04xh", tlvType))
+	}
+	tlvRegistry.Lock()
+	defer tlvRegistry.Unlock()
+	tlvRegistry.registrations[tlvType] = tlvRegistration{decoder: decoder}
+}
+
+// ResetTLV restores the built-in decoder for the given TLV type.
+//
+// For types without a built-in decoder, ResetTLV is equivalent to UnregisterTLV. ResetTLV is safe for concurrent use.
+func ResetTLV(tlvType Type) {
+	tlvRegistry.Lock()
+	defer tlvRegistry.Unlock()
+	registration, found := builtinTLVRegistrations[tlvType]
+	if found {
+		tlvRegistry.registrations[tlvType] = registration
+	} else {
+		delete(tlvRegistry.registrations, tlvType)
+	}
+}
+
+// UnregisterTLV removes the decoder registered for the given TLV type.
+//
+// Afterwards TLVs of this type are treated as unrecognized (see RawTLV). UnregisterTLV is safe for concurrent use.
+func UnregisterTLV(tlvType Type) {
+	tlvRegistry.Lock()
+	defer tlvRegistry.Unlock()
+	delete(tlvRegistry.registrations, tlvType)
+}
+
+// LookupTLVDecoder gets the decoder registered for the given TLV type.
+func LookupTLVDecoder(tlvType Type) (TLVDecoder, bool) {
+	tlvRegistry.RLock()
+	defer tlvRegistry.RUnlock()
+	registration, found := tlvRegistry.registrations[tlvType]
+	return registration.decoder, found
+}
+
+func lookupTLVRegistration(tlvType Type) (tlvRegistration, bool) {
+	tlvRegistry.RLock()
+	defer tlvRegistry.RUnlock()
+	registration, found := tlvRegistry.registrations[tlvType]
+	return registration, found
+}
+
+// RegisteredTLVTypes gets the (sorted) TLV types, a decoder has been registered for.
+func RegisteredTLVTypes() []Type {
+	tlvRegistry.RLock()
+	defer tlvRegistry.RUnlock()
+	return slices.Sorted(maps.Keys(tlvRegistry.registrations))
+}
+
+func decoderOf[T TLV](unmarshal func(value []byte) (T, error)) TLVDecoder {
+	return func(value []byte) (TLV, error) {
+		tlv, err := unmarshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return tlv, nil
+	}
+}
+
+func registerBuiltinTLV(tlvType Type, decoder TLVDecoder) {
+	registration := tlvRegistration{decoder: decoder, builtin: true}
+	builtinTLVRegistrations[tlvType] = registration
+	tlvRegistry.registrations[tlvType] = registration
+}
+
+func init() {
+	registerBuiltinTLV(TypeDeviceModel, decoderOf(unmarshalDeviceModel))
+	registerBuiltinTLV(TypeDeviceName, decoderOf(unmarshalDeviceName))
+	registerBuiltinTLV(TypeDeviceMAC, decoderOf(unmarshalDeviceMAC))
+	registerBuiltinTLV(TypeDeviceLocation, decoderOf(unmarshalDeviceLocation))
+	registerBuiltinTLV(TypeDeviceIP, decoderOf(unmarshalDeviceIP))
+	registerBuiltinTLV(TypeDeviceNetmask, decoderOf(unmarshalDeviceNetmask))
+	registerBuiltinTLV(TypeRouterIP, decoderOf(unmarshalRouterIP))
+	registerBuiltinTLV(TypeDHCPMode, decoderOf(unmarshalDHCPMode))
+	registerBuiltinTLV(TypeFWVersionSlot1, decoderOf(unmarshalFWVersionSlot1))
+	registerBuiltinTLV(TypeFWVersionSlot2, decoderOf(unmarshalFWVersionSlot2))
+	registerBuiltinTLV(TypeNextFWSlot, decoderOf(unmarshalNextFWSlot))
+	registerBuiltinTLV(TypePortStatus, decoderOf(unmarshalPortStatus))
+	registerBuiltinTLV(TypePortStatistic, decoderOf(unmarshalPortStatistic))
+	registerBuiltinTLV(TypePortCount, decoderOf(unmarshalPortCount))
+	registerBuiltinTLV(TypePowerSaving, decoderOf(unmarshalPowerSaving))
+	registerBuiltinTLV(TypeLEDControl, decoderOf(unmarshalLEDControl))
+}
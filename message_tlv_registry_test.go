@@ -0,0 +1,119 @@
+// message_tlv_registry_test.go
+//
+// Copyright (C) 2022-2024 Holger de Carne
+//
+// This software may be modified and distributed under the terms
+// of the MIT license. See the LICENSE file for details.
+
+package nsdp_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdrn-org/go-nsdp"
+)
+
+const testCustomType nsdp.Type = 0x7400
+
+func TestRegisterTLV(t *testing.T) {
+	message := nsdp.NewMessage(nsdp.ReadResponse)
+	message.AppendTLV(nsdp.NewRawTLV(testCustomType, []byte("custom")))
+	marshaledBytes := message.Marshal()
+	nsdp.RegisterTLV(testCustomType, func(value []byte) (nsdp.TLV, error) {
+		return nsdp.NewDeviceName(string(value)), nil
+	})
+	defer nsdp.UnregisterTLV(testCustomType)
+	require.Contains(t, nsdp.RegisteredTLVTypes(), testCustomType)
+	unmarshaled, err := nsdp.UnmarshalMessageStrict(marshaledBytes)
+	require.NoError(t, err)
+	require.Equal(t, nsdp.NewDeviceName("custom"), unmarshaled.Body[0])
+}
+
+func TestRegisterTLVOverride(t *testing.T) {
+	defer nsdp.ResetTLV(nsdp.TypeDeviceName)
+	nsdp.RegisterTLV(nsdp.TypeDeviceName, func(value []byte) (nsdp.TLV, error) {
+		return nsdp.NewRawTLV(nsdp.TypeDeviceName, value), nil
+	})
+	message := nsdp.NewMessage(nsdp.ReadResponse)
+	message.AppendTLV(nsdp.NewDeviceName("Name"))
+	unmarshaled, err := nsdp.UnmarshalMessage(message.Marshal())
+	require.NoError(t, err)
+	require.IsType(t, &nsdp.RawTLV{}, unmarshaled.Body[0])
+}
+
+func TestRegisterTLVOverrideValidation(t *testing.T) {
+	errBadName := errors.New("bad name")
+	defer nsdp.ResetTLV(nsdp.TypeDeviceName)
+	nsdp.RegisterTLV(nsdp.TypeDeviceName, func(value []byte) (nsdp.TLV, error) {
+		if string(value) == "bad" {
+			return nil, errBadName
+		}
+		return nsdp.NewDeviceName(string(value)), nil
+	})
+	good := nsdp.NewMessage(nsdp.ReadResponse)
+	good.AppendTLV(nsdp.NewDeviceName("good"))
+	bad := nsdp.NewMessage(nsdp.ReadResponse)
+	bad.AppendTLV(nsdp.NewDeviceName("bad"))
+	_, err := nsdp.UnmarshalMessage(bad.Marshal())
+	require.ErrorIs(t, err, errBadName)
+	// Re-using a previously decoded message must not bypass the registered decoder
+	decoder := &nsdp.Decoder{}
+	decoded := &nsdp.Message{}
+	require.NoError(t, decoder.Decode(good.Marshal(), decoded))
+	require.ErrorIs(t, decoder.Decode(bad.Marshal(), decoded), errBadName)
+}
+
+func TestRegisterTLVRestoreBuiltin(t *testing.T) {
+	nsdp.RegisterTLV(nsdp.TypeDeviceName, func(value []byte) (nsdp.TLV, error) {
+		return nsdp.NewDeviceName(string(value)), nil
+	})
+	nsdp.ResetTLV(nsdp.TypeDeviceName)
+	msg := nsdp.NewMessage(nsdp.ReadResponse)
+	msg.AppendTLV(nsdp.NewDeviceName("switch"))
+	buf := msg.Marshal()
+	decoder := &nsdp.Decoder{}
+	decoded := &nsdp.Message{}
+	require.NoError(t, decoder.Decode(buf, decoded))
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = decoder.Decode(buf, decoded)
+	})
+	require.Zero(t, allocs)
+}
+
+func TestResetTLVCustomType(t *testing.T) {
+	nsdp.RegisterTLV(testCustomType, func(value []byte) (nsdp.TLV, error) {
+		return nsdp.NewRawTLV(testCustomType, value), nil
+	})
+	nsdp.ResetTLV(testCustomType)
+	_, found := nsdp.LookupTLVDecoder(testCustomType)
+	require.False(t, found)
+}
+
+func TestRegisterTLVConcurrent(t *testing.T) {
+	message := nsdp.NewMessage(nsdp.ReadResponse)
+	message.AppendTLV(nsdp.NewRawTLV(testCustomType, []byte("custom")))
+	marshaledBytes := message.Marshal()
+	defer nsdp.UnregisterTLV(testCustomType)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			nsdp.RegisterTLV(testCustomType, func(value []byte) (nsdp.TLV, error) {
+				return nsdp.NewRawTLV(testCustomType, value), nil
+			})
+			_, err := nsdp.UnmarshalMessage(marshaledBytes)
+			require.NoError(t, err)
+		})
+	}
+	wg.Wait()
+}
+
+func TestRegisterTLVReserved(t *testing.T) {
+	require.Panics(t, func() {
+		nsdp.RegisterTLV(nsdp.TypeEOM, func(value []byte) (nsdp.TLV, error) {
+			return nil, nil
+		})
+	})
+}
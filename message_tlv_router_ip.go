@@ -26,14 +26,12 @@ func NewRouterIP(ip net.IP) *RouterIP {
 }
 
 func unmarshalRouterIP(bytes []byte) (*RouterIP, error) {
-	len := len(bytes)
-	if len == 0 {
-		return EmptyRouterIP(), nil
+	tlv := EmptyRouterIP()
+	err := tlv.unmarshalValue(bytes)
+	if err != nil {
+		return nil, err
 	}
-	if len != 4 && len != 16 {
-		return nil, fmt.Errorf("unexpected router IP length: %d", len)
-	}
-	return NewRouterIP(net.IP(bytes)), nil
+	return tlv, nil
 }
 
 func (tlv *RouterIP) Type() Type {
@@ -48,6 +46,53 @@ func (tlv *RouterIP) Value() []byte {
 	return tlv.IP
 }
 
+func (tlv *RouterIP) appendValue(buf []byte) []byte {
+	return append(buf, tlv.IP...)
+}
+
+func (tlv *RouterIP) unmarshalValue(value []byte) error {
+	len := len(value)
+	if len != 0 && len != 4 && len != 16 {
+		return fmt.Errorf("unexpected router IP length: %d", len)
+	}
+	tlv.IP = append(tlv.IP[:0], value...)
+	return nil
+}
+
 func (tlv *RouterIP) String() string {
 	return fmt.Sprintf("RouterIP(%04xh) %s", TypeRouterIP, tlv.IP)
 }
+
+func (tlv *RouterIP) AppendBinary(buf []byte) ([]byte, error) {
+	return appendTLVBinary(buf, tlv)
+}
+
+func (tlv *RouterIP) MarshalBinary() ([]byte, error) {
+	return appendTLVBinary(nil, tlv)
+}
+
+func (tlv *RouterIP) UnmarshalBinary(data []byte) error {
+	return unmarshalTLVBinary(data, TypeRouterIP, tlv)
+}
+
+type routerIPJSON struct {
+	IP string `json:"ip"`
+}
+
+func (tlv *RouterIP) MarshalJSON() ([]byte, error) {
+	return marshalTLVJSON(TypeRouterIP, &routerIPJSON{IP: formatJSONIP(tlv.IP)})
+}
+
+func (tlv *RouterIP) UnmarshalJSON(data []byte) error {
+	decoded := &routerIPJSON{}
+	err := unmarshalTLVJSON(data, TypeRouterIP, decoded)
+	if err != nil {
+		return err
+	}
+	ip, err := parseJSONIP(decoded.IP)
+	if err != nil {
+		return err
+	}
+	tlv.IP = ip
+	return nil
+}
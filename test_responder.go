@@ -77,9 +77,9 @@ func (responder *TestResponder) listen() {
 	defer responder.conn.Close()
 	defer func() { responder.stopped <- true }()
 	buffer := make([]byte, 8192)
+	log.Printf("NSDP-TestResponder listening on %s", responder.conn.LocalAddr().String())
+	responder.started <- true
 	for _, responseChunk := range responder.responseChunks {
-		log.Printf("NSDP-TestResponder listening on %s", responder.conn.LocalAddr().String())
-		responder.started <- true
 		len, addr, err := responder.conn.ReadFromUDP(buffer)
 		if err != nil {
 			log.Printf("NSDP-TestResponder listening failure; cause: %v", err)